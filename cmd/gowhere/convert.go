@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+// converter writes the rules in another format and reads them back
+type converter struct {
+	write func(out io.Writer, rules *gowhere.RuleSet) ([]gowhere.ConversionWarning, error)
+	read  func(fd io.Reader) (*gowhere.RuleSet, error)
+}
+
+// converters holds the output formats, by name
+var converters = map[string]converter{
+	"htaccess": {gowhere.WriteHtaccess, gowhere.ParseRules},
+	"nginx":    {gowhere.WriteNginx, gowhere.ParseNginx},
+}
+
+func convertCommand(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var to = flags.String("to", "", "the output format (htaccess or nginx)")
+	var output = flags.String("o", "", "the output file (default standard output)")
+	var checkFile = flags.String("check", "",
+		"run the checks in this test file against the converted rules")
+	flags.Usage = func() {
+		fmt.Printf("gowhere convert -to htaccess|nginx [-o file] [-check test file] [-format name] <rules file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) != 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: please specify rules file\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+	conv, ok := converters[*to]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: unknown output format %q\n\n", *to)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	rules := loadRules(remaining[0], *format)
+	var converted bytes.Buffer
+	warnings, err := conv.write(&converted, rules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not convert %s: %v\n", remaining[0], err)
+		os.Exit(2)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", w.String())
+	}
+
+	if *output == "" {
+		os.Stdout.Write(converted.Bytes())
+	} else if err := os.WriteFile(*output, converted.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write %s: %v\n", *output, err)
+		os.Exit(2)
+	}
+
+	if *checkFile == "" {
+		return
+	}
+	convertedRules, err := conv.read(bytes.NewReader(converted.Bytes()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read the converted rules: %v\n", err)
+		os.Exit(2)
+	}
+	checks := loadChecks(*checkFile)
+	results := gowhere.ProcessChecks(convertedRules, checks, gowhere.Settings{})
+	// The line numbers in the results refer to the converted rules.
+	// Report on stderr when the converted rules are written to
+	// stdout, to keep the results out of them.
+	var report io.Writer = os.Stdout
+	if *output == "" {
+		report = os.Stderr
+	}
+	failures := summarizeResults(report, results, false, true, false)
+	if failures > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d checks failed against the converted rules\n", failures)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "All %d checks passed against the converted rules\n", len(checks))
+}
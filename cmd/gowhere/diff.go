@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+func describeResult(matches []gowhere.Match) string {
+	if len(matches) == 0 {
+		return "200 (not redirected)"
+	}
+	final := matches[len(matches)-1]
+	hops := "hops"
+	if len(matches) == 1 {
+		hops = "hop"
+	}
+	return fmt.Sprintf("%s '%s' [line %d] in %d %s",
+		final.Code, final.Match, final.LineNum, len(matches), hops)
+}
+
+// loadCorpus reads the paths to compare from a test file or, if it
+// cannot be parsed as one, from a list of URLs or an access log
+func loadCorpus(filename string) []string {
+	var paths []string
+
+	checks, err := gowhere.ParseChecksFile(filename)
+	if err == nil {
+		for _, c := range checks {
+			paths = append(paths, c.Input)
+		}
+		return paths
+	}
+
+	corpusFile, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read path file %s: %v\n",
+			filename, err)
+		os.Exit(2)
+	}
+	defer corpusFile.Close()
+	traffic, skipped, err := gowhere.ParseTraffic(corpusFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not parse path file %s: %v\n",
+			filename, err)
+		os.Exit(2)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d lines without a path in %s\n",
+			skipped, filename)
+	}
+	for _, t := range traffic {
+		paths = append(paths, t.Path)
+	}
+	return paths
+}
+
+func showRules(title string, rules []gowhere.Rule) {
+	if len(rules) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, r := range rules {
+		fmt.Printf("  %s\n", r.String())
+	}
+}
+
+func diffCommand(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var maxHops = flags.Int("max-hops", 0, "how many hops are allowed")
+	var noEscape = flags.Bool("ne", false,
+		"do not encode redirect destinations (like the NE flag)")
+	flags.Usage = func() {
+		fmt.Printf("gowhere diff [-max-hops N] [-ne] [-format name] <old htaccess file> <new htaccess file> [test, url, or log file]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) < 2 || len(remaining) > 3 {
+		fmt.Fprintf(os.Stderr,
+			"ERROR: please specify the old and new htaccess files\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	oldRules := loadRules(remaining[0], *format)
+	newRules := loadRules(remaining[1], *format)
+
+	// Always include the literal paths from both files, plus any
+	// other paths given.
+	paths := append(oldRules.LiteralPaths(), newRules.LiteralPaths()...)
+	if len(remaining) == 3 {
+		paths = append(paths, loadCorpus(remaining[2])...)
+	}
+
+	settings := gowhere.Settings{
+		MaxHops:  *maxHops,
+		NoEscape: *noEscape,
+	}
+	d := gowhere.DiffRuleSets(oldRules, newRules, paths, settings)
+
+	for _, c := range d.Changed {
+		fmt.Printf("%s\n", c.Path)
+		fmt.Printf("  old: %s\n", describeResult(c.Old))
+		fmt.Printf("  new: %s\n", describeResult(c.New))
+	}
+
+	showRules("Added rules", d.Added)
+	showRules("Removed rules", d.Removed)
+	showRules("Reordered rules", d.Reordered)
+
+	if len(d.Changed) > 0 || len(d.Added) > 0 || len(d.Removed) > 0 ||
+		len(d.Reordered) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d paths changed\n", len(d.Changed))
+		os.Exit(1)
+	}
+}
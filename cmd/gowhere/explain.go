@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+func showAttempt(a *gowhere.Attempt) {
+	fmt.Printf("  %s\n", a.Rule.String())
+	if a.Outcome != gowhere.Matched {
+		fmt.Printf("      no match: %s\n", a.Outcome)
+		return
+	}
+
+	fmt.Printf("      matched\n")
+	if len(a.Captures) > 0 {
+		var groups []string
+		for i, c := range a.Captures {
+			groups = append(groups, fmt.Sprintf("$%d=%q", i, c))
+		}
+		fmt.Printf("      captures: %s\n", strings.Join(groups, " "))
+	}
+	if a.Match == "" {
+		fmt.Printf("      no destination\n")
+	} else if a.Rule.Target != a.Match {
+		fmt.Printf("      target %s expands to %s\n", a.Rule.Target, a.Match)
+	} else {
+		fmt.Printf("      target %s\n", a.Match)
+	}
+}
+
+func showExplanation(e *gowhere.Explanation) {
+	for i, hop := range e.Hops {
+		fmt.Printf("hop %d: %s\n", i+1, hop.Path)
+		for _, a := range hop.Attempts {
+			showAttempt(&a)
+		}
+		if winner := hop.Winner(); winner != nil {
+			fmt.Printf("  winner: [line %d] %s %s\n",
+				winner.Rule.LineNum, winner.Rule.Code, winner.Match)
+		} else {
+			fmt.Printf("  winner: none\n")
+		}
+		fmt.Printf("\n")
+	}
+
+	switch e.End {
+	case gowhere.NoMatch:
+		if len(e.Hops) == 1 {
+			fmt.Printf("result: '%s' is not redirected\n", e.Input)
+			return
+		}
+	case gowhere.CycleDetected:
+		fmt.Printf("result: cycle detected\n")
+		return
+	case gowhere.HopLimitReached:
+		fmt.Printf("result: more than the maximum number of hops\n")
+		return
+	}
+	matches := e.Matches()
+	final := matches[len(matches)-1]
+	fmt.Printf("result: '%s' -> %s '%s' in %d hops\n",
+		e.Input, final.Code, final.Match, len(matches))
+}
+
+func explainCommand(args []string) {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var maxHops = flags.Int("max-hops", 0, "how many hops are allowed")
+	var noEscape = flags.Bool("ne", false,
+		"do not encode redirect destinations (like the NE flag)")
+	flags.Usage = func() {
+		fmt.Printf("gowhere explain [-max-hops N] [-ne] [-format name] <htaccess file> <path>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) != 2 {
+		fmt.Fprintf(os.Stderr,
+			"ERROR: please specify htaccess file and path\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	rules := loadRules(remaining[0], *format)
+	settings := gowhere.Settings{
+		MaxHops:  *maxHops,
+		NoEscape: *noEscape,
+	}
+	showExplanation(rules.Explain(remaining[1], settings))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+// exportFormats holds the names of the export formats
+var exportFormats = map[string]bool{
+	"s3-routing-rules":  true,
+	"meta-refresh-html": true,
+}
+
+// writePages writes each page to a file under the directory
+func writePages(dir string, pages []gowhere.RedirectPage) error {
+	for _, page := range pages {
+		filename := filepath.Join(dir, filepath.FromSlash(page.Filename))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, page.Content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportCommand(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	var inputFormat = rulesFormatFlag(flags, "input-format")
+	var format = flags.String("format", "",
+		"the output format (s3-routing-rules or meta-refresh-html)")
+	var output = flags.String("o", "",
+		"the output file, or the directory for meta-refresh-html (default standard output)")
+	flags.Usage = func() {
+		fmt.Printf("gowhere export -format s3-routing-rules [-o file] [-input-format name] <rules file>\n")
+		fmt.Printf("gowhere export -format meta-refresh-html -o dir [-input-format name] <rules file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) != 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: please specify rules file\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+	if !exportFormats[*format] {
+		fmt.Fprintf(os.Stderr, "ERROR: unknown output format %q\n\n", *format)
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *format == "meta-refresh-html" && *output == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: please specify the output directory with -o\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	rules := loadRules(remaining[0], *inputFormat)
+	var warnings []gowhere.ConversionWarning
+	var err error
+	switch *format {
+	case "s3-routing-rules":
+		var exported bytes.Buffer
+		warnings, err = gowhere.WriteS3RoutingRules(&exported, rules)
+		if err == nil && *output == "" {
+			_, err = os.Stdout.Write(exported.Bytes())
+		} else if err == nil {
+			err = os.WriteFile(*output, exported.Bytes(), 0644)
+		}
+	case "meta-refresh-html":
+		var pages []gowhere.RedirectPage
+		pages, warnings = gowhere.RedirectPages(rules)
+		err = writePages(*output, pages)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "Wrote %d pages to %s\n", len(pages), *output)
+		}
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", w.String())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not export %s: %v\n", remaining[0], err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+func genChecksCommand(args []string) {
+	flags := flag.NewFlagSet("gen-checks", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var noEscape = flags.Bool("ne", false,
+		"do not encode redirect destinations (like the NE flag)")
+	flags.Usage = func() {
+		fmt.Printf("gowhere gen-checks [-ne] [-format name] <htaccess file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) != 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: please specify htaccess file\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	rules := loadRules(remaining[0], *format)
+	settings := gowhere.Settings{
+		NoEscape: *noEscape,
+	}
+
+	untested := 0
+	fmt.Printf("# checks generated from %s\n", remaining[0])
+	for _, g := range gowhere.GenerateChecks(rules, settings) {
+		fmt.Printf("\n# %s\n", g.Rule.String())
+		if g.Check == nil {
+			untested++
+			fmt.Printf("# no check generated: %s\n", g.Problem)
+			continue
+		}
+		fmt.Printf("%s\n", g.Check.String())
+	}
+
+	if untested > 0 {
+		fmt.Fprintf(os.Stderr, "Could not generate checks for %d rules\n", untested)
+		os.Exit(1)
+	}
+}
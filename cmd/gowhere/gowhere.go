@@ -1,78 +1,297 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/dhellmann/gowhere/pkg/gowhere"
 )
 
-func showCheckAndMatches(msg string, check *gowhere.Check, matches []gowhere.Match) {
-	fmt.Printf("%s on line %d: '%s' should produce %s '%s'\n",
-		msg, check.LineNum, check.Input, check.Code, check.Expected)
+func checkLocation(check *gowhere.Check) string {
+	if check.Name != "" {
+		return fmt.Sprintf("line %d (%s)", check.LineNum, check.Name)
+	}
+	return fmt.Sprintf("line %d", check.LineNum)
+}
+
+// matchSource describes where a match came from: the rule for
+// simulated results, or nothing for the responses from a live server
+func matchSource(m gowhere.Match) string {
+	if m.LineNum == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [line %d]", m.LineNum)
+}
+
+func showCheckAndMatches(out io.Writer, msg string, check *gowhere.Check, matches []gowhere.Match) {
+	fmt.Fprintf(out, "%s on %s: '%s' should produce %s '%s'\n",
+		msg, checkLocation(check), check.Input, check.Code, check.Expected)
 	for _, m := range matches {
-		fmt.Printf("    %s -> %s %s [line %d]\n",
-			check.Input, m.Code, m.Match, m.LineNum)
+		fmt.Fprintf(out, "    %s -> %s %s%s\n",
+			check.Input, m.Code, m.Match, matchSource(m))
+	}
+}
+
+func showDiverged(out io.Writer, item *gowhere.Diverged) {
+	check := &item.Check
+	fmt.Fprintf(out, "Redirect chain diverged at hop %d for check on %s: '%s' should go %s\n",
+		item.Hop+1, checkLocation(check), check.Input,
+		strings.Join(check.Chain, " -> "))
+	for i, m := range item.Matches {
+		marker := ""
+		if i == item.Hop {
+			marker = "  <-- expected "
+			if i < len(check.Chain) {
+				marker += check.Chain[i]
+			} else {
+				marker += "no more redirects"
+			}
+		}
+		fmt.Fprintf(out, "    %s -> %s %s%s%s\n",
+			check.Input, m.Code, m.Match, matchSource(m), marker)
+	}
+	if item.Hop >= len(item.Matches) {
+		fmt.Fprintf(out, "    missing hop %d to %s\n",
+			item.Hop+1, check.Chain[item.Hop])
+	}
+}
+
+// textTracer shows the progress of processing the checks as text
+type textTracer struct{}
+
+func (t textTracer) Trace(e gowhere.Event) {
+	switch e.Kind {
+	case gowhere.CheckStarted:
+		fmt.Printf("\ncheck on %s: '%s' should produce %s '%s'\n",
+			checkLocation(e.Check), e.Check.Input, e.Check.Code,
+			e.Check.Expected)
+	case gowhere.LookupStarted:
+		fmt.Printf("firstMatch '%s'\n", e.Path)
+	case gowhere.RuleTried:
+		fmt.Printf("checking: '%s' against %s '%s'\n", e.Path,
+			e.Rule.Directive, e.Rule.Pattern)
+	case gowhere.RuleMatched:
+		fmt.Printf("matched: %s -> %s\n", e.Rule.String(), e.Match)
+	case gowhere.NoMatch:
+		fmt.Printf("no more matches\n")
+	case gowhere.CycleDetected:
+		fmt.Printf("cycle back to '%s'\n", e.Path)
+	case gowhere.HopLimitReached:
+		fmt.Printf("max hops exceeded after %d hops\n", e.Hops)
+	case gowhere.NoTarget:
+		fmt.Printf("no-target redirect\n")
+	case gowhere.Rewritten:
+		fmt.Printf("serving '%s' with code %s without redirecting\n", e.Match, e.Rule.Code)
+	case gowhere.CheckFinished:
+		fmt.Printf("found %d matches\n", len(e.Matches))
 	}
 }
 
-func summarizeResults(results *gowhere.Results, verbose bool,
+func summarizeResults(out io.Writer, results *gowhere.Results, verbose bool,
 	ignoreUntested bool, errorUntested bool) (failures int32) {
 
 	if verbose {
-		fmt.Println("")
+		fmt.Fprintln(out)
 	}
 
 	for _, item := range results.Mismatched {
 		failures++
 		if len(item.Matches) > 0 {
-			showCheckAndMatches("Unexpected rule matched check",
+			showCheckAndMatches(out, "Unexpected rule matched check",
 				&(item.Check), item.Matches)
 		} else {
-			showCheckAndMatches("No rule matched check",
+			showCheckAndMatches(out, "No rule matched check",
 				&(item.Check), item.Matches)
 		}
 	}
 
 	for _, item := range results.Cycles {
 		failures++
-		showCheckAndMatches("Cycle found from rule",
+		showCheckAndMatches(out, "Cycle found from rule",
 			&(item.Check), item.Matches)
 	}
 
 	for _, item := range results.ExceededHops {
 		failures++
-		showCheckAndMatches("Excessive redirects found from rule",
+		showCheckAndMatches(out, "Excessive redirects found from rule",
 			&(item.Check), item.Matches)
 	}
 
+	for _, item := range results.Diverged {
+		failures++
+		showDiverged(out, &item)
+	}
+
+	if verbose && len(results.Skipped) > 0 {
+		fmt.Fprintf(out, "Skipped %d checks not selected by -tags or -run\n",
+			len(results.Skipped))
+	}
+
 	if !ignoreUntested {
 		for _, item := range results.Unmatched {
 			if errorUntested {
 				failures++
 			}
-			fmt.Printf("Untested rule %s\n", item.String())
+			fmt.Fprintf(out, "Untested rule %s\n", item.String())
 		}
 	}
 
 	return failures
 }
 
+// isTextCheckFile tests whether the test file uses the text format
+func isTextCheckFile(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml", ".json":
+		return false
+	}
+	return true
+}
+
+// updateCheckFile rewrites the test file with the updated checks and
+// shows what changed
+func updateCheckFile(filename string, updates []gowhere.CheckUpdate) {
+	if len(updates) == 0 {
+		fmt.Printf("No checks to update in %s\n", filename)
+		return
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read test file %s: %v\n", filename, err)
+		os.Exit(2)
+	}
+	checkFile, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read test file %s: %v\n", filename, err)
+		os.Exit(2)
+	}
+	var updated bytes.Buffer
+	err = gowhere.UpdateChecks(checkFile, &updated, updates)
+	checkFile.Close()
+	if err == nil {
+		err = os.WriteFile(filename, updated.Bytes(), info.Mode())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not update test file %s: %v\n", filename, err)
+		os.Exit(2)
+	}
+
+	for _, u := range updates {
+		old := strings.TrimSpace(u.Check.Code + " " + u.Check.Expected)
+		fmt.Printf("Updated check on %s: '%s' %s -> %s\n",
+			checkLocation(&u.Check), u.Check.Input, old,
+			strings.TrimSpace(u.Code+" "+u.Expected))
+	}
+	fmt.Printf("Updated %d checks in %s\n", len(updates), filename)
+}
+
+// rulesFormatFlag adds the option with the format of the rules file
+// to the flags
+func rulesFormatFlag(flags *flag.FlagSet, name string) *string {
+	return flags.String(name, "", fmt.Sprintf(
+		"the format of the rules file (%s; default chosen from the file)",
+		strings.Join(gowhere.RuleFormats(), ", ")))
+}
+
+// loadRules reads the rules file, exiting if it cannot be parsed
+func loadRules(filename string, format string) *gowhere.RuleSet {
+	rules, err := gowhere.ParseRulesFile(filename, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read rules file %s: %v\n",
+			filename, err)
+		os.Exit(2)
+	}
+	return rules
+}
+
+// loadChecks reads the test file, exiting if it cannot be parsed
+func loadChecks(filename string) []gowhere.Check {
+	checks, err := gowhere.ParseChecksFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not parse test file %s: %v\n",
+			filename, err)
+		os.Exit(2)
+	}
+	return checks
+}
+
+// setFilters selects the checks to run using the values of the -tags
+// and -run options, exiting if they cannot be understood
+func setFilters(settings *gowhere.Settings, tags string, run string) {
+	if tags != "" {
+		settings.Tags = strings.Split(tags, ",")
+	}
+	if run != "" {
+		var err error
+		settings.Run, err = regexp.Compile(run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not understand -run regexp %s: %v\n",
+				run, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// commands holds the subcommands, by name
+var commands = map[string]func(args []string){
+	"convert":    convertCommand,
+	"diff":       diffCommand,
+	"explain":    explainCommand,
+	"export":     exportCommand,
+	"gen-checks": genChecksCommand,
+	"repl":       replCommand,
+	"replay":     replayCommand,
+	"serve":      serveCommand,
+	"verify":     verifyCommand,
+}
+
 func usage() {
 	fmt.Printf("gowhere [-h]\n")
-	fmt.Printf("gowhere [-v] [-ignore-untested] [-error-untested] [-max-hops N] <htaccess file> <test file>\n")
+	fmt.Printf("gowhere [-v] [-ignore-untested] [-error-untested] [-max-hops N] [-ne] [-j N] [-tags a,b] [-run regexp] [-update] [-format name] <htaccess file> <test file>\n")
+	fmt.Printf("gowhere convert -to htaccess|nginx [-o file] [-check test file] [-format name] <rules file>\n")
+	fmt.Printf("gowhere diff [-max-hops N] [-ne] [-format name] <old htaccess file> <new htaccess file> [test, url, or log file]\n")
+	fmt.Printf("gowhere explain [-max-hops N] [-ne] [-format name] <htaccess file> <path>\n")
+	fmt.Printf("gowhere export -format s3-routing-rules|meta-refresh-html [-o file or dir] [-input-format name] <rules file>\n")
+	fmt.Printf("gowhere gen-checks [-ne] [-format name] <htaccess file>\n")
+	fmt.Printf("gowhere repl [-max-hops N] [-ne] [-format name] <htaccess file>\n")
+	fmt.Printf("gowhere replay [-max-hops N] [-ne] [-top N] [-format name] <htaccess file> <urls or log file>\n")
+	fmt.Printf("gowhere serve [-root dir] [-addr host:port] [-ne] [-format name] <htaccess file>\n")
+	fmt.Printf("gowhere verify -base-url URL [-compare htaccess file [-format name]] [-max-hops N] [-tags a,b] [-run regexp] <test file>\n")
 	fmt.Printf("\n")
 	flag.PrintDefaults()
 	fmt.Printf("\n")
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	var ignoreUntested = flag.Bool("ignore-untested", false,
 		"ignore untested rules")
 	var errorUntested = flag.Bool("error-untested", false,
 		"error if there are untested rules")
 	var maxHops = flag.Int("max-hops", 0, "how many hops are allowed")
+	var noEscape = flag.Bool("ne", false,
+		"do not encode redirect destinations (like the NE flag)")
+	var tags = flag.String("tags", "",
+		"only run checks with one of these comma-separated tags")
+	var run = flag.String("run", "",
+		"only run checks with inputs matching this regexp")
+	var jobs = flag.Int("j", 1, "how many checks to evaluate in parallel")
+	var update = flag.Bool("update", false,
+		"rewrite the expected results of failing checks in the test file")
+	var format = rulesFormatFlag(flag.CommandLine, "format")
 	var verbose = flag.Bool("v", false, "turn on verbose output")
 	var help = flag.Bool("h", false, "show this help output")
 
@@ -96,37 +315,32 @@ func main() {
 		os.Exit(1)
 	}
 
-	htaccessFile, err := os.Open(remaining[0])
-	defer htaccessFile.Close()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not read htaccess file %s: %v\n",
-			remaining[0], err)
-		os.Exit(2)
-	}
-	rules, err := gowhere.ParseRules(htaccessFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not parse htaccess file %s: %v\n",
-			remaining[0], err)
-		os.Exit(2)
+	rules := loadRules(remaining[0], *format)
+
+	if *update && !isTextCheckFile(remaining[1]) {
+		fmt.Fprintf(os.Stderr, "Can only update text test files\n")
+		os.Exit(1)
 	}
 
-	testFile, err := os.Open(remaining[1])
-	defer testFile.Close()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not read test file %s: %v\n",
-			remaining[1], err)
-		os.Exit(2)
+	checks := loadChecks(remaining[1])
+
+	settings := gowhere.Settings{
+		Verbose:  *verbose,
+		MaxHops:  *maxHops,
+		NoEscape: *noEscape,
+		Jobs:     *jobs,
 	}
-	checks, err := gowhere.ParseChecks(testFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not parse test file %s: %v\n",
-			remaining[1], err)
-		os.Exit(2)
+	if *verbose {
+		settings.Tracer = textTracer{}
 	}
-
-	settings := gowhere.Settings{*verbose, *maxHops}
+	setFilters(&settings, *tags, *run)
 	results := gowhere.ProcessChecks(rules, checks, settings)
-	failures := summarizeResults(results, *verbose,
+	if *update {
+		updateCheckFile(remaining[1], results.Updates())
+		// The updated checks no longer fail.
+		results.Mismatched = nil
+	}
+	failures := summarizeResults(os.Stdout, results, *verbose,
 		*ignoreUntested, *errorUntested)
 
 	if failures > 0 {
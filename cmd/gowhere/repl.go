@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+const replHelp = `Enter a path to see its redirect chain, or one of these commands:
+
+  :reload         read the htaccess file again
+  :append <file>  add the last result as a check to a test file
+  :help           show this help
+  :quit           exit (or use Ctrl-D)
+`
+
+// replSession holds the state of an interactive session
+type replSession struct {
+	filename string
+	format   string
+	rules    *gowhere.RuleSet
+	settings gowhere.Settings
+	// the result for the last path entered
+	last *gowhere.Check
+}
+
+// reload reads the htaccess file again, keeping the old rules if
+// there is an error
+func (s *replSession) reload() error {
+	rules, err := gowhere.ParseRulesFile(s.filename, s.format)
+	if err != nil {
+		return err
+	}
+	s.rules = rules
+	return nil
+}
+
+// lookup shows the redirect chain for the path
+func (s *replSession) lookup(path string) {
+	matches := s.rules.FindMatches(&gowhere.Check{Input: path}, s.settings)
+	if len(matches) == 0 {
+		fmt.Printf("    %s is not redirected\n", path)
+	}
+	from := path
+	for _, m := range matches {
+		fmt.Printf("    %s -> %s %s [line %d]\n", from, m.Code, m.Match, m.LineNum)
+		from = m.Match
+	}
+	s.last = gowhere.CheckFromMatches(path, matches)
+}
+
+// appendCheck adds the last result to the end of a text test file
+func (s *replSession) appendCheck(filename string) error {
+	if s.last == nil {
+		return fmt.Errorf("no result to append yet")
+	}
+	if !isTextCheckFile(filename) {
+		return fmt.Errorf("can only append to text test files")
+	}
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, s.last.String())
+	return err
+}
+
+// command runs one of the ":" commands, returning false when the
+// session should end
+func (s *replSession) command(line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ":quit", ":q", ":exit":
+		return false
+	case ":help", ":h":
+		fmt.Print(replHelp)
+	case ":reload", ":r":
+		if err := s.reload(); err != nil {
+			fmt.Printf("Could not reload %s: %v\n", s.filename, err)
+		} else {
+			fmt.Printf("Reloaded %s\n", s.filename)
+		}
+	case ":append", ":a":
+		if len(fields) != 2 {
+			fmt.Printf("Please specify the test file\n")
+			break
+		}
+		if err := s.appendCheck(fields[1]); err != nil {
+			fmt.Printf("Could not append to %s: %v\n", fields[1], err)
+		} else {
+			fmt.Printf("Added '%s' to %s\n", s.last.String(), fields[1])
+		}
+	default:
+		fmt.Printf("Unknown command %s, try :help\n", fields[0])
+	}
+	return true
+}
+
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".gowhere_history")
+}
+
+func replCommand(args []string) {
+	flags := flag.NewFlagSet("repl", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var maxHops = flags.Int("max-hops", 0, "how many hops are allowed")
+	var noEscape = flags.Bool("ne", false,
+		"do not encode redirect destinations (like the NE flag)")
+	flags.Usage = func() {
+		fmt.Printf("gowhere repl [-max-hops N] [-ne] [-format name] <htaccess file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) != 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: please specify htaccess file\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	session := replSession{
+		filename: remaining[0],
+		format:   *format,
+		rules:    loadRules(remaining[0], *format),
+		settings: gowhere.Settings{
+			MaxHops:  *maxHops,
+			NoEscape: *noEscape,
+		},
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:      "gowhere> ",
+		HistoryFile: historyFile(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not start the interactive session: %v\n", err)
+		os.Exit(2)
+	}
+	defer rl.Close()
+
+	fmt.Printf("Loaded %s. Type :help for help.\n", session.filename)
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			if !session.command(line) {
+				break
+			}
+			continue
+		}
+		session.lookup(line)
+	}
+}
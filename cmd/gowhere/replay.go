@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+func showOutcomes(title string, outcomes []gowhere.ReplayOutcome, limit int) {
+	if len(outcomes) == 0 {
+		return
+	}
+	fmt.Printf("\n%s (%d paths):\n", title, len(outcomes))
+	for i, o := range outcomes {
+		if limit > 0 && i >= limit {
+			fmt.Printf("  ... %d more\n", len(outcomes)-limit)
+			break
+		}
+		fmt.Printf("  %s (%d hits)\n", o.Path, o.Hits)
+		for _, m := range o.Matches {
+			fmt.Printf("    -> %s %s [line %d]\n", m.Code, m.Match, m.LineNum)
+		}
+	}
+}
+
+func showReplayReport(report *gowhere.ReplayReport, limit int) {
+	fmt.Printf("%d requests for %d unique paths\n", report.Hits, report.Paths)
+
+	fmt.Printf("\nFinal status:\n")
+	var codes []string
+	for code := range report.Status {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		hits := report.Status[code]
+		fmt.Printf("  %s: %d (%.1f%%)\n", code, hits,
+			100*float64(hits)/float64(report.Hits))
+	}
+
+	showOutcomes("Cycles", report.Cycles, limit)
+	showOutcomes("Long chains", report.LongChains, limit)
+	showOutcomes("Ending without a destination", report.Nowhere, limit)
+
+	if len(report.TopRules) > 0 {
+		fmt.Printf("\nTop rules:\n")
+		for i, h := range report.TopRules {
+			if limit > 0 && i >= limit {
+				break
+			}
+			fmt.Printf("  %d hits: %s\n", h.Hits, h.Rule.String())
+		}
+	}
+}
+
+func replayCommand(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var maxHops = flags.Int("max-hops", 0,
+		"how many hops are allowed before a chain is reported as long (default 1)")
+	var noEscape = flags.Bool("ne", false,
+		"do not encode redirect destinations (like the NE flag)")
+	var top = flags.Int("top", 10,
+		"how many items to show in each list (0 for all)")
+	flags.Usage = func() {
+		fmt.Printf("gowhere replay [-max-hops N] [-ne] [-top N] [-format name] <htaccess file> <urls or log file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) != 2 {
+		fmt.Fprintf(os.Stderr,
+			"ERROR: please specify htaccess file and traffic file\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	rules := loadRules(remaining[0], *format)
+
+	trafficFile, err := os.Open(remaining[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read traffic file %s: %v\n",
+			remaining[1], err)
+		os.Exit(2)
+	}
+	defer trafficFile.Close()
+	traffic, skipped, err := gowhere.ParseTraffic(trafficFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not parse traffic file %s: %v\n",
+			remaining[1], err)
+		os.Exit(2)
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d lines without a path in %s\n",
+			skipped, remaining[1])
+	}
+
+	settings := gowhere.Settings{
+		MaxHops:  *maxHops,
+		NoEscape: *noEscape,
+	}
+	showReplayReport(gowhere.Replay(rules, traffic, settings), *top)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+// staticFiles serves the files in a directory
+type staticFiles struct {
+	files http.Handler
+}
+
+func (s staticFiles) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if strings.HasSuffix(req.URL.Path, "/index.html") {
+		// Serve the index page directly, instead of redirecting
+		// to the directory like http.FileServer, so the only
+		// redirects come from the rules.
+		req = req.Clone(req.Context())
+		req.URL.Path = strings.TrimSuffix(req.URL.Path, "index.html")
+		req.URL.RawPath = ""
+	}
+	s.files.ServeHTTP(w, req)
+}
+
+// statusRecorder remembers the status of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests shows each request and the response
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(&r, req)
+		fmt.Printf("%s %s -> %d %s\n", req.Method, req.URL.RequestURI(),
+			r.status, w.Header().Get("Location"))
+	})
+}
+
+func serveCommand(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var root = flags.String("root", ".", "the directory with the static files")
+	var addr = flags.String("addr", "localhost:8080", "the address to listen on")
+	var noEscape = flags.Bool("ne", false,
+		"do not encode redirect destinations (like the NE flag)")
+	flags.Usage = func() {
+		fmt.Printf("gowhere serve [-root dir] [-addr host:port] [-ne] [-format name] <htaccess file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) > 1 {
+		// Allow the options to come after the htaccess file.
+		flags.Parse(remaining[1:])
+		remaining = append(remaining[:1], flags.Args()...)
+	}
+	if len(remaining) != 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: please specify htaccess file\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	settings := gowhere.Settings{
+		NoEscape: *noEscape,
+	}
+	files := staticFiles{http.FileServer(http.Dir(*root))}
+	handler, err := gowhere.NewFileHandler(remaining[0], *format, files, settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read htaccess file %s: %v\n",
+			remaining[0], err)
+		os.Exit(2)
+	}
+	handler.OnReload = func(filename string, err error) {
+		if err != nil {
+			fmt.Printf("Could not reload %s: %v\n", filename, err)
+		} else {
+			fmt.Printf("Reloaded %s\n", filename)
+		}
+	}
+
+	fmt.Printf("Serving %s with the rules from %s on http://%s/\n",
+		*root, remaining[0], *addr)
+	if err := http.ListenAndServe(*addr, logRequests(handler)); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+func showDiscrepancy(d *gowhere.Discrepancy) {
+	var differences []string
+	if d.CodeDiffers {
+		differences = append(differences, "status")
+	}
+	if d.LocationDiffers {
+		differences = append(differences, "location")
+	}
+	if d.ChainDiffers {
+		differences = append(differences, "chain")
+	}
+	fmt.Printf("Server differs from the rules in %s for check on %s: '%s'\n",
+		strings.Join(differences, ", "),
+		checkLocation(&d.Check), d.Check.Input)
+	fmt.Printf("  rules:  %s\n", describeChain(d.Check.Input, d.Simulated))
+	fmt.Printf("  server: %s\n", describeChain(d.Check.Input, d.Live))
+}
+
+// describeChain shows each hop of a redirect chain on one line
+func describeChain(input string, matches []gowhere.Match) string {
+	if len(matches) == 0 {
+		return input + " (not redirected)"
+	}
+	parts := []string{input}
+	for _, m := range matches {
+		parts = append(parts, fmt.Sprintf("%s %s%s", m.Code, m.Match, matchSource(m)))
+	}
+	return strings.Join(parts, " -> ")
+}
+
+func verifyCommand(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	var format = rulesFormatFlag(flags, "format")
+	var baseURL = flags.String("base-url", "",
+		"the URL of the server to check, such as http://localhost:8080")
+	var maxHops = flags.Int("max-hops", 0, "how many hops are allowed")
+	var tags = flags.String("tags", "",
+		"only run checks with one of these comma-separated tags")
+	var run = flags.String("run", "",
+		"only run checks with inputs matching this regexp")
+	var compare = flags.String("compare", "",
+		"also run the checks through this htaccess file and report where it differs from the server")
+	var verbose = flags.Bool("v", false, "turn on verbose output")
+	flags.Usage = func() {
+		fmt.Printf("gowhere verify -base-url URL [-compare htaccess file [-format name]] [-max-hops N] [-tags a,b] [-run regexp] <test file>\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	remaining := flags.Args()
+	if len(remaining) != 1 || *baseURL == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: please specify -base-url and test file\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	verifier, err := gowhere.NewVerifier(*baseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not understand -base-url: %v\n", err)
+		os.Exit(1)
+	}
+	checks := loadChecks(remaining[0])
+
+	settings := gowhere.Settings{
+		Verbose: *verbose,
+		MaxHops: *maxHops,
+	}
+	if *verbose {
+		settings.Tracer = textTracer{}
+	}
+	setFilters(&settings, *tags, *run)
+
+	results, chains, err := verifier.VerifyChecks(checks, settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	// There are no rules to report as untested.
+	failures := summarizeResults(os.Stdout, results, *verbose, true, false)
+
+	if *compare != "" {
+		rules := loadRules(*compare, *format)
+		// Only show the progress of the live checks.
+		settings.Tracer = nil
+		settings.Verbose = false
+		for _, d := range gowhere.CrossCheck(rules, chains, settings) {
+			failures++
+			showDiscrepancy(&d)
+		}
+	}
+
+	if failures > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d failures\n", failures)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/dhellmann/gowhere
+
+go 1.21
+
+require (
+	github.com/chzyer/readline v1.5.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.13.0 // indirect
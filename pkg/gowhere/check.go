@@ -2,26 +2,66 @@ package gowhere
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 // Check represents a test for one Rule
 type Check struct {
 	// The line of the input file where the check was found
 	LineNum int
+	// A short name for the check, from structured check files
+	Name string
+	// A longer explanation of the check, from structured check
+	// files
+	Description string
+	// Labels for selecting groups of checks
+	Tags []string
 	// The input to give to the RuleSet
 	Input string
 	// The expected HTTP response code
 	Code string
 	// The expected destination of the redirection
 	Expected string
+	// Compare the expected destination to the encoded value of the
+	// Location header exactly, instead of comparing the normalized
+	// paths
+	Exact bool
+	// The maximum number of hops allowed to reach the destination,
+	// overriding Settings.MaxHops when it is greater than 0
+	MaxHops int
+	// The expected destination of each hop of the redirection, in
+	// order
+	Chain []string
 }
 
+// optionRE matches the "name=value" options that may follow the
+// positional values on a check line.
+var optionRE = regexp.MustCompile(`^[a-z][a-z-]*=`)
+
 // NewCheck creates a Check from the strings on the input line
 func NewCheck(lineNum int, params []string) (*Check, error) {
 	var t Check
 
 	t.LineNum = lineNum
 
+	// Options come after the positional values.
+	for len(params) > 0 && optionRE.MatchString(params[len(params)-1]) {
+		err := t.setOption(params[len(params)-1])
+		if err != nil {
+			return nil, fmt.Errorf("Could not understand check on line %d: %v",
+				lineNum, err)
+		}
+		params = params[:len(params)-1]
+	}
+
+	if len(params) >= 2 && len(t.Chain) > 0 && SamePath(t.Chain[0], params[0]) {
+		// The chain may start with the input, to make it
+		// easier to read.
+		t.Chain = t.Chain[1:]
+	}
+
 	if len(params) == 3 {
 		// input code expected
 		t.Input = params[0]
@@ -41,3 +81,124 @@ func NewCheck(lineNum int, params []string) (*Check, error) {
 	return nil, fmt.Errorf("Could not understand check on line %d: %v",
 		lineNum, params)
 }
+
+// CheckFromMatches creates a Check expecting the final result of the
+// redirect chain, such as one returned by FindMatches.
+func CheckFromMatches(input string, matches []Match) *Check {
+	c := Check{Input: input, Code: "200"}
+	if len(matches) > 0 {
+		final := matches[len(matches)-1]
+		c.Code = final.Code
+		c.Expected = final.Match
+	}
+	return &c
+}
+
+// String returns the Check in the format read by ParseChecks
+func (c *Check) String() string {
+	parts := []string{c.Input, c.Code}
+	if c.Expected != "" {
+		parts = append(parts, c.Expected)
+	}
+	if c.Exact {
+		parts = append(parts, "exact=true")
+	}
+	if c.MaxHops > 0 {
+		parts = append(parts, fmt.Sprintf("max-hops=%d", c.MaxHops))
+	}
+	if len(c.Chain) > 0 {
+		parts = append(parts, "chain="+strings.Join(c.Chain, ","))
+	}
+	if len(c.Tags) > 0 {
+		parts = append(parts, "tags="+strings.Join(c.Tags, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// setOption updates the Check based on a "name=value" option string
+func (c *Check) setOption(option string) error {
+	parts := strings.SplitN(option, "=", 2)
+	name, value := parts[0], parts[1]
+
+	switch name {
+	case "exact":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("bad value for %s: %v", name, err)
+		}
+		c.Exact = b
+	case "max-hops":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad value for %s: %q", name, value)
+		}
+		c.MaxHops = n
+	case "chain":
+		c.Chain = strings.Split(value, ",")
+	case "tags":
+		c.Tags = strings.Split(value, ",")
+	default:
+		return fmt.Errorf("unknown option %q", name)
+	}
+
+	return nil
+}
+
+// Expects tests whether the destination of a redirect is the one the
+// Check expects.
+func (c *Check) Expects(location string) bool {
+	if c.Exact {
+		return c.Expected == location
+	}
+	return SamePath(c.Expected, location)
+}
+
+// Selected tests whether the Check should be run, based on the tag
+// and input filters in the settings. A Check is selected if it has
+// at least one of the tags and its input matches the regexp.
+func (c *Check) Selected(settings Settings) bool {
+	if settings.Run != nil && !settings.Run.MatchString(c.Input) {
+		return false
+	}
+	if len(settings.Tags) == 0 {
+		return true
+	}
+	for _, want := range settings.Tags {
+		for _, tag := range c.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxHops returns the number of hops allowed for the Check
+func (c *Check) maxHops(settings Settings) int {
+	if c.MaxHops > 0 {
+		return c.MaxHops
+	}
+	return settings.MaxHops
+}
+
+// Diverges returns the index of the first hop in matches that does
+// not follow the expected chain, or -1 if the chain is followed or
+// the Check does not have an expected chain.
+func (c *Check) Diverges(matches []Match) int {
+	if len(c.Chain) == 0 {
+		return -1
+	}
+	for i, m := range matches {
+		if i >= len(c.Chain) {
+			return i
+		}
+		expected := Check{Expected: c.Chain[i], Exact: c.Exact}
+		if !expected.Expects(m.Match) {
+			return i
+		}
+	}
+	if len(matches) < len(c.Chain) {
+		return len(matches)
+	}
+	return -1
+}
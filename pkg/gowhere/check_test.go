@@ -0,0 +1,79 @@
+package gowhere
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewCheckChain(t *testing.T) {
+	c, err := NewCheck(1, []string{"/a", "301", "/c",
+		"chain=/a,/b,/c", "max-hops=2"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(c.Chain) != 2 || c.Chain[0] != "/b" || c.Chain[1] != "/c" {
+		t.Errorf("got chain %v expected [/b /c]", c.Chain)
+	}
+	if c.MaxHops != 2 {
+		t.Errorf("got max hops %d expected 2", c.MaxHops)
+	}
+}
+
+func TestCheckDiverges(t *testing.T) {
+	c := Check{Input: "/a", Code: "301", Expected: "/c",
+		Chain: []string{"/b", "/c"}}
+	var tests = []struct {
+		hops []string
+		want int
+	}{
+		{[]string{"/b", "/c"}, -1},
+		{[]string{"/x", "/c"}, 0},
+		{[]string{"/b"}, 1},
+		{[]string{"/b", "/c", "/d"}, 2},
+	}
+
+	for n, test := range tests {
+		var matches []Match
+		for _, h := range test.hops {
+			matches = append(matches, Match{Match: h})
+		}
+		got := c.Diverges(matches)
+		if got != test.want {
+			t.Errorf("test %d: Diverges(%v) == %d, expected %d",
+				n, test.hops, got, test.want)
+		}
+	}
+}
+
+func TestCheckString(t *testing.T) {
+	var tests = []string{
+		"/a 301 /b",
+		"/a 410",
+		"/a 200",
+		"/a 301 /c exact=true max-hops=2 chain=/b,/c tags=api,docs",
+	}
+
+	for n, line := range tests {
+		c, err := NewCheck(1, strings.Fields(line))
+		if err != nil {
+			t.Fatalf("test %d: got error: %v", n, err)
+		}
+		if c.String() != line {
+			t.Errorf("test %d: got %q expected %q", n, c.String(), line)
+		}
+	}
+}
+
+func TestCheckFromMatches(t *testing.T) {
+	c := CheckFromMatches("/a", nil)
+	if c.String() != "/a 200" {
+		t.Errorf("got %q expected '/a 200'", c.String())
+	}
+	c = CheckFromMatches("/a", []Match{
+		{Rule{Code: "301"}, "/b"},
+		{Rule{Code: "302"}, "/c"},
+	})
+	if c.String() != "/a 302 /c" {
+		t.Errorf("got %q expected '/a 302 /c'", c.String())
+	}
+}
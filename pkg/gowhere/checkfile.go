@@ -0,0 +1,210 @@
+package gowhere
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// statusCode holds an HTTP response code given either as a number or
+// a string in a structured check file
+type statusCode string
+
+func (c *statusCode) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*c = statusCode(n.String())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("code must be a number or string: %s", data)
+	}
+	*c = statusCode(s)
+	return nil
+}
+
+// checkSpec is the representation of a Check in the structured
+// (YAML and JSON) check file formats
+type checkSpec struct {
+	Name        string     `yaml:"name" json:"name"`
+	Description string     `yaml:"description" json:"description"`
+	Input       string     `yaml:"input" json:"input"`
+	Code        statusCode `yaml:"code" json:"code"`
+	Expected    string     `yaml:"expected" json:"expected"`
+	Exact       bool       `yaml:"exact" json:"exact"`
+	MaxHops     int        `yaml:"max-hops" json:"max-hops"`
+	Chain       []string   `yaml:"chain" json:"chain"`
+	Tags        []string   `yaml:"tags" json:"tags"`
+}
+
+// checkSpecFields lists the keys allowed in a structured check
+var checkSpecFields = map[string]bool{
+	"name":        true,
+	"description": true,
+	"input":       true,
+	"code":        true,
+	"expected":    true,
+	"exact":       true,
+	"max-hops":    true,
+	"chain":       true,
+	"tags":        true,
+}
+
+// toCheck validates the checkSpec and converts it to a Check
+func (s *checkSpec) toCheck(lineNum int) (*Check, error) {
+	if s.Input == "" {
+		return nil, fmt.Errorf("Missing input for check on line %d", lineNum)
+	}
+	if s.Code == "" {
+		return nil, fmt.Errorf("Missing code for check on line %d", lineNum)
+	}
+	if s.MaxHops < 0 {
+		return nil, fmt.Errorf("Bad max-hops for check on line %d: %d",
+			lineNum, s.MaxHops)
+	}
+
+	t := Check{
+		LineNum:     lineNum,
+		Name:        s.Name,
+		Description: s.Description,
+		Input:       s.Input,
+		Code:        string(s.Code),
+		Expected:    s.Expected,
+		Exact:       s.Exact,
+		MaxHops:     s.MaxHops,
+		Chain:       s.Chain,
+		Tags:        s.Tags,
+	}
+	if len(t.Chain) > 0 && SamePath(t.Chain[0], t.Input) {
+		t.Chain = t.Chain[1:]
+	}
+	return &t, nil
+}
+
+// ParseChecksYAML reads the rule checks from a YAML document
+// containing a list of checks and returns a slice of Check
+// objects. Stops on the first error parsing the file.
+func ParseChecksYAML(fd io.Reader) ([]Check, error) {
+	var checks []Check
+	var doc yaml.Node
+
+	err := yaml.NewDecoder(fd).Decode(&doc)
+	if err == io.EOF {
+		return checks, nil
+	}
+	if err != nil {
+		return checks, fmt.Errorf("Could not parse checks: %v", err)
+	}
+
+	list := &doc
+	if list.Kind == yaml.DocumentNode && len(list.Content) > 0 {
+		list = list.Content[0]
+	}
+	if list.Kind != yaml.SequenceNode {
+		return checks, fmt.Errorf("Expected a list of checks on line %d",
+			list.Line)
+	}
+
+	for _, item := range list.Content {
+		if item.Kind != yaml.MappingNode {
+			return checks, fmt.Errorf("Could not understand check on line %d",
+				item.Line)
+		}
+		for i := 0; i < len(item.Content); i += 2 {
+			key := item.Content[i]
+			if !checkSpecFields[key.Value] {
+				return checks, fmt.Errorf("Unknown field %q in check on line %d",
+					key.Value, key.Line)
+			}
+		}
+
+		var spec checkSpec
+		if err := item.Decode(&spec); err != nil {
+			return checks, fmt.Errorf("Could not understand check on line %d: %v",
+				item.Line, err)
+		}
+		t, err := spec.toCheck(item.Line)
+		if err != nil {
+			return checks, err
+		}
+		checks = append(checks, *t)
+	}
+	return checks, nil
+}
+
+// ParseChecksJSON reads the rule checks from a JSON document
+// containing a list of checks and returns a slice of Check
+// objects. Stops on the first error parsing the file.
+func ParseChecksJSON(fd io.Reader) ([]Check, error) {
+	var checks []Check
+
+	data, err := io.ReadAll(fd)
+	if err != nil {
+		return checks, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return checks, nil
+	}
+
+	// lineAt returns the line number of the first value at or
+	// after the offset
+	lineAt := func(offset int64) int {
+		rest := data[offset:]
+		start := offset + int64(len(rest)-len(bytes.TrimLeft(rest, " \t\r\n,")))
+		return bytes.Count(data[:start], []byte("\n")) + 1
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return checks, fmt.Errorf("Could not parse checks: %v", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return checks, fmt.Errorf("Expected a list of checks on line 1")
+	}
+
+	for dec.More() {
+		lineNum := lineAt(dec.InputOffset())
+		var spec checkSpec
+		if err := dec.Decode(&spec); err != nil {
+			return checks, fmt.Errorf("Could not understand check on line %d: %v",
+				lineNum, err)
+		}
+		t, err := spec.toCheck(lineNum)
+		if err != nil {
+			return checks, err
+		}
+		checks = append(checks, *t)
+	}
+	return checks, nil
+}
+
+// ParseChecksFile reads the rule checks from the named file and
+// returns a slice of Check objects. The format of the file is
+// determined by its extension: ".yaml" and ".yml" files are parsed
+// with ParseChecksYAML, ".json" files are parsed with
+// ParseChecksJSON, and all other files are parsed with ParseChecks.
+func ParseChecksFile(filename string) ([]Check, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return ParseChecksYAML(fd)
+	case ".json":
+		return ParseChecksJSON(fd)
+	}
+	return ParseChecks(fd)
+}
@@ -0,0 +1,107 @@
+package gowhere
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseChecksYAML(t *testing.T) {
+	data := []byte(`# checks for the old pages
+- name: old page
+  description: The old page moved under each project.
+  input: /project/abc/old_page.html
+  code: 301
+  expected: /project/abc/new_page.html
+  max-hops: 1
+  tags: [release-2024, api]
+
+- input: /project/abc/new_page.html
+  code: "200"
+`)
+	checks, err := ParseChecksYAML(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("got %d checks expected 2", len(checks))
+	}
+	c := checks[0]
+	if c.LineNum != 2 {
+		t.Errorf("got line %d expected 2", c.LineNum)
+	}
+	if c.Name != "old page" {
+		t.Errorf("got name %q expected 'old page'", c.Name)
+	}
+	if c.Code != "301" {
+		t.Errorf("got code %s expected 301", c.Code)
+	}
+	if c.Expected != "/project/abc/new_page.html" {
+		t.Errorf("got expected %s", c.Expected)
+	}
+	if c.MaxHops != 1 {
+		t.Errorf("got max hops %d expected 1", c.MaxHops)
+	}
+	if len(c.Tags) != 2 || c.Tags[1] != "api" {
+		t.Errorf("got tags %v expected [release-2024 api]", c.Tags)
+	}
+	if checks[1].LineNum != 10 {
+		t.Errorf("got line %d expected 10", checks[1].LineNum)
+	}
+	if checks[1].Code != "200" {
+		t.Errorf("got code %s expected 200", checks[1].Code)
+	}
+}
+
+func TestParseChecksYAMLUnknownField(t *testing.T) {
+	data := []byte(`- input: /a
+  code: 301
+  expect: /b
+`)
+	_, err := ParseChecksYAML(bytes.NewReader(data))
+	if err == nil {
+		t.Errorf("should have an error for unknown field")
+	}
+}
+
+func TestParseChecksYAMLMissingCode(t *testing.T) {
+	data := []byte(`- input: /a
+`)
+	_, err := ParseChecksYAML(bytes.NewReader(data))
+	if err == nil {
+		t.Errorf("should have an error for missing code")
+	}
+}
+
+func TestParseChecksJSON(t *testing.T) {
+	data := []byte(`[
+  {"name": "old page", "input": "/a", "code": 301, "expected": "/b",
+   "chain": ["/a", "/b"]},
+  {"input": "/c", "code": "410"}
+]`)
+	checks, err := ParseChecksJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("got %d checks expected 2", len(checks))
+	}
+	if checks[0].LineNum != 2 || checks[1].LineNum != 4 {
+		t.Errorf("got lines %d and %d expected 2 and 4",
+			checks[0].LineNum, checks[1].LineNum)
+	}
+	if checks[0].Code != "301" || checks[1].Code != "410" {
+		t.Errorf("got codes %s and %s expected 301 and 410",
+			checks[0].Code, checks[1].Code)
+	}
+	if len(checks[0].Chain) != 1 || checks[0].Chain[0] != "/b" {
+		t.Errorf("got chain %v expected [/b]", checks[0].Chain)
+	}
+}
+
+func TestParseChecksJSONUnknownField(t *testing.T) {
+	data := []byte(`[{"input": "/a", "code": 301, "target": "/b"}]`)
+	_, err := ParseChecksJSON(bytes.NewReader(data))
+	if err == nil {
+		t.Errorf("should have an error for unknown field")
+	}
+}
@@ -0,0 +1,124 @@
+package gowhere
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConversionWarning describes a Rule whose behavior cannot be
+// preserved exactly when it is converted to another format.
+type ConversionWarning struct {
+	Rule    Rule
+	Message string
+}
+
+func (w ConversionWarning) String() string {
+	return fmt.Sprintf("%s: %s", w.Rule.String(), w.Message)
+}
+
+// conversionWarnings collects the warnings about the rules while they
+// are converted
+type conversionWarnings []ConversionWarning
+
+// add records a warning about the rule
+func (w *conversionWarnings) add(r Rule, format string, args ...interface{}) {
+	*w = append(*w, ConversionWarning{r, fmt.Sprintf(format, args...)})
+}
+
+// sorted returns the warnings in the order of the rules in the file
+func (w conversionWarnings) sorted() []ConversionWarning {
+	sort.SliceStable(w, func(a, b int) bool {
+		return w[a].Rule.LineNum < w[b].Rule.LineNum
+	})
+	return w
+}
+
+// literalPath returns the only path matched by the rule, decoded, if
+// it matches a single path
+func (r *Rule) literalPath() (string, bool) {
+	switch r.Directive {
+	case "redirect":
+		return DecodePath(r.Pattern), true
+	case "redirectmatch":
+		if p, ok := literalPattern(r.Pattern); ok {
+			return DecodePath(p), true
+		}
+	case "netlify":
+		if !strings.ContainsAny(r.Pattern, ":*") {
+			// The trailing slash is optional, so use the
+			// path without it.
+			p := strings.TrimSuffix(r.Pattern, "/")
+			if p == "" {
+				p = "/"
+			}
+			return DecodePath(p), true
+		}
+	}
+	return "", false
+}
+
+// nginxTargetError returns an error if the rules were read from an
+// nginx configuration file and the destination of the rule uses a
+// variable other than the numbered captures, such as $uri. The
+// htaccess, S3, and meta refresh formats have nothing to replace those
+// variables with, so WriteHtaccess, WriteS3RoutingRules, and
+// RedirectPages do not convert those rules.
+func nginxTargetError(rules *RuleSet, r *Rule) error {
+	if _, ok := rules.dialect.(*nginxServer); !ok {
+		return nil
+	}
+	if n := nginxNamedVariable(r.Target); n != "" {
+		return fmt.Errorf("the nginx variable $%s has no equivalent", n)
+	}
+	return nil
+}
+
+// droppedRewrites adds a warning for each internal rewrite in rules
+// read from an nginx configuration file, since converting the rules
+// loses them and the paths they change are handled differently.
+func droppedRewrites(rules *RuleSet, warnings *conversionWarnings) {
+	n, ok := rules.dialect.(*nginxServer)
+	if !ok {
+		return
+	}
+	for _, r := range n.internalRewrites() {
+		warnings.add(r, "cannot convert: the internal rewrite to %s does not redirect, so the paths it changes are handled differently",
+			r.Target)
+	}
+}
+
+// probePaths returns paths matched by the rule, to look for other
+// rules that handle the same paths
+func (r *Rule) probePaths() []string {
+	if r.Directive == "redirect" {
+		return []string{r.Pattern}
+	}
+	return r.Samples()
+}
+
+// overlapping calls found for each pair of rules, in order, where
+// the second rule also matches one of the paths matched by the
+// first. Regular expressions are compared using sample paths, so not
+// every overlap is found.
+func overlapping(rules *RuleSet, found func(first, second int, path string)) {
+	seen := make(map[[2]int]bool)
+	for i := range rules.rules {
+		r := &rules.rules[i]
+		for _, p := range r.probePaths() {
+			for _, j := range rules.candidates(p) {
+				if j == i || rules.rules[j].Explain(p, Settings{}).Outcome != Matched {
+					continue
+				}
+				pair := [2]int{i, j}
+				if j < i {
+					pair = [2]int{j, i}
+				}
+				if !seen[pair] {
+					seen[pair] = true
+					found(pair[0], pair[1], p)
+				}
+			}
+		}
+	}
+}
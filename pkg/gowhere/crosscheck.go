@@ -0,0 +1,56 @@
+package gowhere
+
+// Discrepancy describes a Check for which the simulated rules and a
+// live server produce different redirects.
+type Discrepancy struct {
+	Check Check
+	// The redirect chains from the rules and from the server
+	Simulated []Match
+	Live      []Match
+	// What is different
+	CodeDiffers     bool
+	LocationDiffers bool
+	ChainDiffers    bool
+}
+
+// sameChain tests whether two redirect chains go through the same
+// locations with the same response codes
+func sameChain(a, b []Match) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Code != b[i].Code || !SamePath(a[i].Match, b[i].Match) {
+			return false
+		}
+	}
+	return true
+}
+
+// CrossCheck runs the Check of each redirect chain returned by a live
+// server (from VerifyChecks) through the rules, and reports the Checks
+// for which the results differ, whether or not they match what the
+// Check expects.
+func CrossCheck(rules *RuleSet, chains []LiveChain, settings Settings) []Discrepancy {
+	var discrepancies []Discrepancy
+	for _, chain := range chains {
+		check := chain.Check
+		live := chain.Matches
+		simulated := findMatches(rules, &check, settings)
+
+		simCode, simTarget := finalResult(simulated)
+		liveCode, liveTarget := finalResult(live)
+		d := Discrepancy{
+			Check:           check,
+			Simulated:       simulated,
+			Live:            live,
+			CodeDiffers:     simCode != liveCode,
+			LocationDiffers: !SamePath(simTarget, liveTarget),
+			ChainDiffers:    !sameChain(simulated, live),
+		}
+		if d.CodeDiffers || d.LocationDiffers || d.ChainDiffers {
+			discrepancies = append(discrepancies, d)
+		}
+	}
+	return discrepancies
+}
@@ -0,0 +1,64 @@
+package gowhere
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCrossCheck(t *testing.T) {
+	server := redirectServer(map[string][2]string{
+		"/a":      {"301", "/b"},
+		"/b":      {"301", "/c"},
+		"/same":   {"301", "/other"},
+		"/code":   {"302", "/other"},
+		"/target": {"301", "/elsewhere"},
+	})
+	defer server.Close()
+
+	rules := []byte(`redirect 301 /a /c
+redirect 301 /same /other
+redirect 301 /code /other
+redirect 301 /target /other
+redirect 410 /gone
+`)
+	rs, _ := ParseRules(bytes.NewReader(rules))
+	checks := []Check{
+		{LineNum: 1, Input: "/a", Code: "301", Expected: "/c"},
+		{LineNum: 2, Input: "/same", Code: "301", Expected: "/other"},
+		{LineNum: 3, Input: "/code", Code: "301", Expected: "/other"},
+		{LineNum: 4, Input: "/target", Code: "301", Expected: "/other"},
+		{LineNum: 5, Input: "/gone", Code: "410"},
+		{LineNum: 6, Input: "/ok", Code: "200"},
+	}
+	v, _ := NewVerifier(server.URL)
+	_, chains, err := v.VerifyChecks(checks, Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	discrepancies := CrossCheck(rs, chains, Settings{})
+
+	expected := []struct {
+		line                      int
+		code, location, chainDiff bool
+	}{
+		// same final result through a different chain
+		{1, false, false, true},
+		{3, true, false, true},
+		{4, false, true, true},
+		// not redirected by the server
+		{5, true, false, true},
+	}
+	if len(discrepancies) != len(expected) {
+		t.Fatalf("got %d discrepancies expected %d: %v",
+			len(discrepancies), len(expected), discrepancies)
+	}
+	for i, e := range expected {
+		d := discrepancies[i]
+		if d.Check.LineNum != e.line || d.CodeDiffers != e.code ||
+			d.LocationDiffers != e.location || d.ChainDiffers != e.chainDiff {
+			t.Errorf("got line %d code %v location %v chain %v expected %v",
+				d.Check.LineNum, d.CodeDiffers, d.LocationDiffers,
+				d.ChainDiffers, e)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package gowhere
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns holds the names accepted in the header row of a
+// spreadsheet of rules, for each column
+var csvColumns = map[string]string{
+	"source":      "source",
+	"from":        "source",
+	"target":      "target",
+	"to":          "target",
+	"destination": "target",
+	"code":        "code",
+	"status":      "code",
+	"type":        "type",
+}
+
+// csvDefaultColumns is the order of the columns in a spreadsheet
+// without a header row
+var csvDefaultColumns = []string{"source", "target", "code", "type"}
+
+// csvDirectives maps the type column of a spreadsheet to the
+// directive of the rule
+var csvDirectives = map[string]string{
+	"":        "redirect",
+	"literal": "redirect",
+	"regex":   "redirectmatch",
+	"regexp":  "redirectmatch",
+}
+
+// csvHeader returns the position of each column if the row is a
+// header naming the columns. A header needs a source column, and the
+// columns with other names, such as notes, are ignored.
+func csvHeader(row []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, name := range row {
+		if column, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[column] = i
+		}
+	}
+	if _, ok := columns["source"]; !ok {
+		return nil, false
+	}
+	return columns, true
+}
+
+// csvRule creates a Rule from one row of a spreadsheet
+func csvRule(lineNum int, row []string, columns map[string]int) (*Rule, error) {
+	get := func(column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	directive, ok := csvDirectives[strings.ToLower(get("type"))]
+	if !ok {
+		return nil, fmt.Errorf("Could not understand type '%s' on line %d", get("type"), lineNum)
+	}
+	code := get("code")
+	if code == "" {
+		code = "301"
+	}
+	if c, ok := statusNames[strings.ToLower(code)]; ok {
+		code = c
+	}
+	if _, err := httpStatus(code); err != nil {
+		return nil, fmt.Errorf("Could not understand code '%s' on line %d", code, lineNum)
+	}
+	params := []string{directive, code, get("source"), get("target")}
+	if params[3] == "" {
+		if code != "410" {
+			return nil, fmt.Errorf("Missing target on line %d: %v", lineNum, row)
+		}
+		params = params[:3]
+	}
+	return NewRule(lineNum, params)
+}
+
+// parseSpreadsheet reads the rules from a spreadsheet with the given
+// separator
+func parseSpreadsheet(fd io.Reader, separator rune) (*RuleSet, error) {
+	var rules []Rule
+	input := csv.NewReader(fd)
+	input.Comma = separator
+	input.FieldsPerRecord = -1
+	input.LazyQuotes = true
+	input.TrimLeadingSpace = true
+
+	columns := make(map[string]int)
+	for i, name := range csvDefaultColumns {
+		columns[name] = i
+	}
+	first := true
+	for {
+		row, err := input.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return NewRuleSet(rules), err
+		}
+		lineNum, _ := input.FieldPos(0)
+
+		if first {
+			first = false
+			// Spreadsheets saved as UTF-8 often start with a
+			// byte order mark.
+			row[0] = strings.TrimPrefix(row[0], "\ufeff")
+			if header, ok := csvHeader(row); ok {
+				columns = header
+				continue
+			}
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		r, err := csvRule(lineNum, row, columns)
+		if err != nil {
+			return NewRuleSet(rules), err
+		}
+		rules = append(rules, *r)
+	}
+	return NewRuleSet(rules), nil
+}
+
+// ParseCSV reads the rules from a spreadsheet exported as comma
+// separated values and returns a RuleSet containing all of them. Each
+// row holds the source path, the target, the code (301 if empty), and
+// the type ("literal", the default, or "regex" for a regular
+// expression), in that order unless the first row is a header naming
+// the columns. The row numbers are used as line numbers. Stops on the
+// first error parsing the file.
+func ParseCSV(fd io.Reader) (*RuleSet, error) {
+	return parseSpreadsheet(fd, ',')
+}
+
+// ParseTSV reads the rules from a spreadsheet exported as tab
+// separated values, in the same format as ParseCSV.
+func ParseTSV(fd io.Reader) (*RuleSet, error) {
+	return parseSpreadsheet(fd, '\t')
+}
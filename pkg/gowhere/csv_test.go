@@ -0,0 +1,96 @@
+package gowhere
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	data := `Type,From,To,Status
+literal,/a,/b,
+regex,^/docs/(.*)$,/new/$1,302
+
+literal,/gone,,410
+,"/with space",/c,307
+`
+	rs, err := ParseCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, r := range rs.rules {
+		actual = append(actual, r.String())
+	}
+	expected := []string{
+		"[line 2] redirect /a 301 /b",
+		"[line 3] redirectmatch ^/docs/(.*)$ 302 /new/$1",
+		"[line 5] redirect /gone 410 ",
+		"[line 6] redirect /with space 307 /c",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q expected %q", actual, expected)
+	}
+	if m := rs.Lookup("/with%20space", Settings{}); m == nil || m.Match != "/c" {
+		t.Errorf("got %v expected a redirect to /c", m)
+	}
+}
+
+func TestParseCSVExtraColumns(t *testing.T) {
+	data := "source,target,code,notes,owner\n/a,/b,302,moved in 2020,web\n"
+	rs, err := ParseCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.rules) != 1 || rs.rules[0].String() != "[line 2] redirect /a 302 /b" {
+		t.Errorf("got %v", rs.rules)
+	}
+}
+
+func TestParseCSVByteOrderMark(t *testing.T) {
+	data := "\ufeffsource,target,code\n/a,/b,302\n"
+	rs, err := ParseCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.rules) != 1 || rs.rules[0].String() != "[line 2] redirect /a 302 /b" {
+		t.Errorf("got %v", rs.rules)
+	}
+}
+
+func TestParseTSV(t *testing.T) {
+	data := "/a\t/b\n/docs/(.*)\t/new/$1\t302\tregex\n"
+	rs, err := ParseTSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, r := range rs.rules {
+		actual = append(actual, r.String())
+	}
+	expected := []string{
+		"[line 1] redirect /a 301 /b",
+		"[line 2] redirectmatch /docs/(.*) 302 /new/$1",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q expected %q", actual, expected)
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		data string
+	}{
+		{"unknown type", "/a,/b,301,glob\n"},
+		{"missing target", "/a,,301\n"},
+		{"bad regexp", "^/a(,/b,301,regex\n"},
+		{"bad code", "/a,/b,30l\n"},
+	}
+
+	for n, test := range tests {
+		if _, err := ParseCSV(strings.NewReader(test.data)); err == nil {
+			t.Errorf("test %d: expected an error", n)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package gowhere
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// PathChange describes a path that is handled differently by two
+// RuleSets.
+type PathChange struct {
+	Path string
+	// The redirect chains from the old and new RuleSets
+	Old []Match
+	New []Match
+	// What is different about the final result
+	CodeChanged   bool
+	TargetChanged bool
+	HopsChanged   bool
+}
+
+// RuleSetDiff holds the differences between two RuleSets
+type RuleSetDiff struct {
+	// paths with a different final code, target, or hop count
+	Changed []PathChange
+	// rules only in the new RuleSet
+	Added []Rule
+	// rules only in the old RuleSet
+	Removed []Rule
+	// rules in both RuleSets that moved relative to the others,
+	// from the new RuleSet
+	Reordered []Rule
+}
+
+// finalResult returns the final code and destination of a redirect
+// chain
+func finalResult(matches []Match) (string, string) {
+	if len(matches) == 0 {
+		return "200", ""
+	}
+	final := matches[len(matches)-1]
+	return final.Code, final.Match
+}
+
+// DiffRuleSets resolves each unique path through both RuleSets and reports
+// the paths with a different final code, target, or number of hops,
+// as well as the rules added, removed, and reordered.
+func DiffRuleSets(oldRules, newRules *RuleSet, paths []string, settings Settings) *RuleSetDiff {
+	var d RuleSetDiff
+
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		check := Check{Input: p}
+		oldMatches := oldRules.FindMatches(&check, settings)
+		newMatches := newRules.FindMatches(&check, settings)
+
+		oldCode, oldTarget := finalResult(oldMatches)
+		newCode, newTarget := finalResult(newMatches)
+		change := PathChange{
+			Path:          p,
+			Old:           oldMatches,
+			New:           newMatches,
+			CodeChanged:   oldCode != newCode,
+			TargetChanged: !SamePath(oldTarget, newTarget),
+			HopsChanged:   len(oldMatches) != len(newMatches),
+		}
+		if change.CodeChanged || change.TargetChanged || change.HopsChanged {
+			d.Changed = append(d.Changed, change)
+		}
+	}
+
+	d.Added, d.Removed, d.Reordered = diffRules(oldRules.rules, newRules.rules)
+	return &d
+}
+
+// ruleKeys returns a key for each rule that is the same for
+// equivalent rules in different files, with a counter to tell
+// duplicates apart.
+func ruleKeys(rules []Rule) []string {
+	counts := make(map[string]int)
+	keys := make([]string, len(rules))
+	for i, r := range rules {
+		key := strings.Join([]string{strings.ToLower(r.Directive),
+			r.Code, r.Pattern, r.Target}, " ")
+		counts[key]++
+		keys[i] = fmt.Sprintf("%s #%d", key, counts[key])
+	}
+	return keys
+}
+
+// diffRules compares two lists of rules
+func diffRules(oldRules, newRules []Rule) (added, removed, reordered []Rule) {
+	oldKeys := ruleKeys(oldRules)
+	newKeys := ruleKeys(newRules)
+
+	newPositions := make(map[string]int)
+	for i, k := range newKeys {
+		newPositions[k] = i
+	}
+	oldPositions := make(map[string]int)
+	for i, k := range oldKeys {
+		oldPositions[k] = i
+	}
+
+	for i, k := range newKeys {
+		if _, ok := oldPositions[k]; !ok {
+			added = append(added, newRules[i])
+		}
+	}
+
+	// The positions in the new rules of the rules in both lists, in
+	// the old order. The rules in the longest increasing
+	// subsequence kept their relative order, and the rest moved.
+	var common []int
+	for i, k := range oldKeys {
+		if pos, ok := newPositions[k]; ok {
+			common = append(common, pos)
+		} else {
+			removed = append(removed, oldRules[i])
+		}
+	}
+	kept := longestIncreasing(common)
+	for _, pos := range common {
+		if !kept[pos] {
+			reordered = append(reordered, newRules[pos])
+		}
+	}
+	sort.Slice(reordered, func(i, j int) bool {
+		return reordered[i].LineNum < reordered[j].LineNum
+	})
+
+	return added, removed, reordered
+}
+
+// longestIncreasing returns the values in the longest increasing
+// subsequence of the distinct values.
+func longestIncreasing(values []int) map[int]bool {
+	// tails[n] is the position in values of the smallest value
+	// ending an increasing subsequence of length n+1
+	var tails []int
+	prev := make([]int, len(values))
+	for i, v := range values {
+		n := sort.Search(len(tails), func(j int) bool {
+			return values[tails[j]] >= v
+		})
+		if n > 0 {
+			prev[i] = tails[n-1]
+		} else {
+			prev[i] = -1
+		}
+		if n == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[n] = i
+		}
+	}
+
+	kept := make(map[int]bool)
+	if len(tails) == 0 {
+		return kept
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		kept[values[i]] = true
+	}
+	return kept
+}
+
+// literalPattern returns the only path matching the regexp, if the
+// regexp is a literal anchored at both ends.
+func literalPattern(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+	if re.Op != syntax.OpConcat || len(re.Sub) < 3 {
+		return "", false
+	}
+	first, last := re.Sub[0], re.Sub[len(re.Sub)-1]
+	if first.Op != syntax.OpBeginText || last.Op != syntax.OpEndText {
+		return "", false
+	}
+	var literal strings.Builder
+	for _, sub := range re.Sub[1 : len(re.Sub)-1] {
+		if !literalPrefix(sub, &literal) {
+			return "", false
+		}
+	}
+	return literal.String(), true
+}
+
+// LiteralPaths returns the paths matched by the rules with literal
+// patterns, in order and without duplicates, to use as a corpus for
+// comparing RuleSets.
+func (rs *RuleSet) LiteralPaths() []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, r := range rs.rules {
+		p := ""
+		switch r.Directive {
+		case "redirect":
+			p = r.Pattern
+		case "redirectmatch":
+			p, _ = literalPattern(r.Pattern)
+		}
+		if p != "" && !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
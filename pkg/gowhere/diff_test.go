@@ -0,0 +1,68 @@
+package gowhere
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRuleSets(t *testing.T) {
+	oldRules, _ := ParseRules(bytes.NewReader([]byte(`redirect 301 /a /b
+redirect 301 /c /d
+redirect 301 /e /f
+redirect 301 /k /l
+redirect 301 /m /n
+redirect 301 /g /h
+redirect 301 /x /y
+`)))
+	newRules, _ := ParseRules(bytes.NewReader([]byte(`redirect 301 /a /b
+redirect 301 /g /h
+redirect 301 /c /d2
+redirect 301 /e /f
+redirect 301 /k /l
+redirect 301 /m /n
+redirect 302 /x /y
+redirect 301 /h /i
+`)))
+	paths := append(oldRules.LiteralPaths(), newRules.LiteralPaths()...)
+	d := DiffRuleSets(oldRules, newRules, paths, Settings{})
+
+	var changed []string
+	for _, c := range d.Changed {
+		changed = append(changed, c.Path)
+	}
+	// /g now goes on to /i, /c goes to a new place, /x has a new
+	// code, and /h is redirected.
+	want := []string{"/c", "/g", "/x", "/h"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("got changed paths %v expected %v", changed, want)
+	}
+	if !d.Changed[1].HopsChanged || d.Changed[1].CodeChanged {
+		t.Errorf("got change %+v expected only the hops to change", d.Changed[1])
+	}
+	if !d.Changed[2].CodeChanged || d.Changed[2].TargetChanged {
+		t.Errorf("got change %+v expected only the code to change", d.Changed[2])
+	}
+
+	if len(d.Added) != 3 {
+		t.Errorf("got %d added rules expected 3: %v", len(d.Added), d.Added)
+	}
+	if len(d.Removed) != 2 {
+		t.Errorf("got %d removed rules expected 2: %v", len(d.Removed), d.Removed)
+	}
+	if len(d.Reordered) != 1 || d.Reordered[0].Pattern != "/g" {
+		t.Errorf("got reordered rules %v expected /g", d.Reordered)
+	}
+}
+
+func TestLiteralPaths(t *testing.T) {
+	rs, _ := ParseRules(bytes.NewReader([]byte(`redirect 301 /a /b
+redirectmatch 301 ^/c/$ /d
+redirectmatch 301 ^/e/(.*)$ /f/$1
+redirect 410 /a
+`)))
+	want := []string{"/a", "/c/"}
+	if got := rs.LiteralPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v expected %v", got, want)
+	}
+}
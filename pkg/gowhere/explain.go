@@ -0,0 +1,120 @@
+package gowhere
+
+// Hop describes looking for the rule matching one path in a redirect
+// chain.
+type Hop struct {
+	// The path compared to the rules
+	Path string
+	// Every rule compared to the path, in order, ending with the
+	// rule that matched (if any)
+	Attempts []Attempt
+}
+
+// Winner returns the Attempt for the rule that matched the path, or
+// nil if no rule matched.
+func (h *Hop) Winner() *Attempt {
+	if len(h.Attempts) == 0 {
+		return nil
+	}
+	last := &h.Attempts[len(h.Attempts)-1]
+	if last.Outcome != Matched {
+		return nil
+	}
+	return last
+}
+
+// Explanation describes how the rules handle an input path
+type Explanation struct {
+	Input string
+	Hops  []Hop
+	// Why the redirect chain ended (NoMatch, CycleDetected,
+	// HopLimitReached, NoTarget, or Rewritten)
+	End EventKind
+}
+
+// Matches returns the redirect chain, the same way FindMatches does.
+func (e *Explanation) Matches() []Match {
+	var r []Match
+	for _, hop := range e.Hops {
+		winner := hop.Winner()
+		if winner == nil {
+			break
+		}
+		if e.End == CycleDetected && len(r) == len(e.Hops)-1 {
+			// The redirect back to a path already in the
+			// chain is not included.
+			break
+		}
+		r = append(r, Match{*winner.Rule, winner.Match})
+	}
+	return r
+}
+
+// explainHop compares the rules to one path in a redirect chain
+func (rs *RuleSet) explainHop(path string, settings Settings) Hop {
+	hop := Hop{Path: path}
+	if rs.dialect != nil {
+		// Only the rule chosen by the dialect is known.
+		if m := rs.dialect.firstMatch(path, settings); m != nil {
+			hop.Attempts = append(hop.Attempts, Attempt{Rule: &m.Rule,
+				Path: path, Outcome: Matched, Match: m.Match})
+		}
+		return hop
+	}
+	for i := range rs.rules {
+		a := rs.rules[i].Explain(path, settings)
+		hop.Attempts = append(hop.Attempts, a)
+		if a.Outcome == Matched {
+			break
+		}
+	}
+	return hop
+}
+
+// Explain follows the redirect chain for the input path, comparing
+// every rule to each path in order instead of using the index, and
+// records the details of each comparison.
+func (rs *RuleSet) Explain(input string, settings Settings) *Explanation {
+	e := Explanation{Input: input}
+	check := Check{Input: input}
+	maxHops := check.maxHops(settings)
+	seen := make(map[string]bool)
+
+	path := input
+	for {
+		hop := rs.explainHop(path, settings)
+		e.Hops = append(e.Hops, hop)
+
+		winner := hop.Winner()
+		if winner == nil {
+			e.End = NoMatch
+			break
+		}
+
+		location := NormalizePath(winner.Match)
+		if seen[location] {
+			e.End = CycleDetected
+			break
+		}
+		seen[location] = true
+
+		if maxHops > 0 && len(e.Hops) > maxHops {
+			e.End = HopLimitReached
+			break
+		}
+
+		if servesTarget(winner.Rule.Code, winner.Match) {
+			e.End = Rewritten
+			break
+		}
+
+		if winner.Match == "" {
+			e.End = NoTarget
+			break
+		}
+
+		path = winner.Match
+	}
+
+	return &e
+}
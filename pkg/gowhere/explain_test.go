@@ -0,0 +1,79 @@
+package gowhere
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var explainRules = []byte(`redirectmatch 301 ^/project/([^/]+)/old_page.html$ /project/$1/new_page.html
+redirect 301 /project/def/new_page.html /project/def/other_page.html
+redirect 410 /gone.html
+redirect 301 /cycle/a /cycle/b
+redirect 301 /cycle/b /cycle/a
+`)
+
+func TestRuleSetExplain(t *testing.T) {
+	rs, _ := ParseRules(bytes.NewReader(explainRules))
+	e := rs.Explain("/project/def/old_page.html", Settings{})
+
+	if len(e.Hops) != 3 {
+		t.Fatalf("got %d hops expected 3", len(e.Hops))
+	}
+	if e.End != NoMatch {
+		t.Errorf("got end %v expected %v", e.End, NoMatch)
+	}
+
+	first := e.Hops[0].Winner()
+	if first == nil || first.Rule.LineNum != 1 {
+		t.Fatalf("got first winner %v expected rule on line 1", first)
+	}
+	want := []string{"/project/def/old_page.html", "def"}
+	if !reflect.DeepEqual(first.Captures, want) {
+		t.Errorf("got captures %v expected %v", first.Captures, want)
+	}
+
+	second := e.Hops[1]
+	if len(second.Attempts) != 2 {
+		t.Fatalf("got %d attempts expected 2", len(second.Attempts))
+	}
+	if second.Attempts[0].Outcome != RegexpMismatch {
+		t.Errorf("got outcome %v expected %v",
+			second.Attempts[0].Outcome, RegexpMismatch)
+	}
+
+	last := e.Hops[2]
+	if len(last.Attempts) != 5 || last.Winner() != nil {
+		t.Errorf("expected all rules to be tried without a winner: %v", last)
+	}
+	if last.Attempts[1].Outcome != LiteralMismatch {
+		t.Errorf("got outcome %v expected %v",
+			last.Attempts[1].Outcome, LiteralMismatch)
+	}
+}
+
+func TestRuleSetExplainSameAsFindMatches(t *testing.T) {
+	rs, _ := ParseRules(bytes.NewReader(explainRules))
+	var tests = []struct {
+		input string
+		end   EventKind
+	}{
+		{"/project/def/old_page.html", NoMatch},
+		{"/gone.html", NoTarget},
+		{"/cycle/a", CycleDetected},
+		{"/not/redirected", NoMatch},
+	}
+
+	for n, test := range tests {
+		e := rs.Explain(test.input, Settings{})
+		if e.End != test.end {
+			t.Errorf("test %d: got end %v expected %v", n, e.End, test.end)
+		}
+		c := Check{Input: test.input}
+		want := rs.FindMatches(&c, Settings{})
+		got := e.Matches()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("test %d: got matches %v expected %v", n, got, want)
+		}
+	}
+}
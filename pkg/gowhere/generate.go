@@ -0,0 +1,61 @@
+package gowhere
+
+import (
+	"fmt"
+)
+
+// GeneratedCheck holds the Check created to test a Rule, or the reason
+// one could not be created.
+type GeneratedCheck struct {
+	Rule Rule
+	// nil if the rule could not be tested
+	Check   *Check
+	Problem string
+}
+
+// GenerateChecks creates a Check for each Rule, using an input path
+// that the Rule is the first to match and expecting the final result
+// of the redirect chain, so that running the checks tests every rule.
+func GenerateChecks(rules *RuleSet, settings Settings) []GeneratedCheck {
+	var result []GeneratedCheck
+
+	for _, r := range rules.rules {
+		g := GeneratedCheck{Rule: r}
+
+		examples := r.Samples()
+		if len(examples) == 0 {
+			g.Problem = "could not find a path matching the pattern"
+			result = append(result, g)
+			continue
+		}
+
+		// Use the first example not handled by an earlier rule.
+		var shadow *Match
+		for _, input := range examples {
+			first := rules.firstMatch(input, settings)
+			if first != nil && first.LineNum != r.LineNum {
+				if shadow == nil {
+					shadow = first
+				}
+				continue
+			}
+
+			check := Check{Input: input}
+			matches, end := rules.followChain(&check, settings)
+			if end == CycleDetected {
+				g.Problem = fmt.Sprintf("'%s' leads to a redirect cycle", input)
+				break
+			}
+			g.Check = CheckFromMatches(input, matches)
+			break
+		}
+		if g.Check == nil && g.Problem == "" {
+			g.Problem = fmt.Sprintf("'%s' is handled first by %s",
+				examples[0], shadow.Rule.String())
+		}
+
+		result = append(result, g)
+	}
+
+	return result
+}
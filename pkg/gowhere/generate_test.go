@@ -0,0 +1,70 @@
+package gowhere
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGenerateChecks(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /b /c
+redirectmatch 301 ^/docs/(.*)$ /new/$1
+redirect 410 /gone
+redirectmatch 301 ^/docs/x$ /shadowed
+redirect 301 /cycle/a /cycle/b
+redirect 301 /cycle/b /cycle/a
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	generated := GenerateChecks(rs, Settings{})
+
+	expected := []string{
+		"/a 301 /c",
+		"/b 301 /c",
+		"/docs/x 301 /new/x",
+		"/gone 410",
+		"",
+		"",
+		"",
+	}
+	if len(generated) != len(expected) {
+		t.Fatalf("got %d checks expected %d", len(generated), len(expected))
+	}
+	for i, g := range generated {
+		actual := ""
+		if g.Check != nil {
+			actual = g.Check.String()
+		}
+		if actual != expected[i] {
+			t.Errorf("rule %d: got '%s' expected '%s' (%s)",
+				i, actual, expected[i], g.Problem)
+		}
+	}
+	if !strings.Contains(generated[4].Problem, "[line 3]") {
+		t.Errorf("got problem %q expected shadowing by line 3",
+			generated[4].Problem)
+	}
+	if !strings.Contains(generated[5].Problem, "cycle") {
+		t.Errorf("got problem %q expected a cycle", generated[5].Problem)
+	}
+}
+
+func TestGenerateChecksCoverage(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirectmatch 302 ^/blog/(\d+)/(.*)\.html$ /posts/$2
+redirectmatch 301 ^/(en|fr)/old$ /$1/new
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	var checks []Check
+	for _, g := range GenerateChecks(rs, Settings{}) {
+		if g.Check == nil {
+			t.Fatalf("no check for %s: %s", g.Rule.String(), g.Problem)
+		}
+		checks = append(checks, *g.Check)
+	}
+	results := ProcessChecks(rs, checks, Settings{})
+	if len(results.Mismatched) != 0 || len(results.Unmatched) != 0 {
+		t.Errorf("got mismatched %v and unmatched %v",
+			results.Mismatched, results.Unmatched)
+	}
+}
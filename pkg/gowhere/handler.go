@@ -0,0 +1,260 @@
+package gowhere
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is how often a Handler created with
+// NewFileHandler checks whether the rules file has changed, unless
+// ReloadInterval is set.
+const DefaultReloadInterval = time.Second
+
+// httpStatus converts the code of a Rule to an HTTP status
+func httpStatus(code string) (int, error) {
+	status, err := strconv.Atoi(code)
+	if err != nil || status < 100 || status > 999 {
+		return 0, fmt.Errorf("Could not understand status code %q", code)
+	}
+	return status, nil
+}
+
+// servesTarget tests whether a rule with the code and destination
+// serves the destination with that status instead of redirecting to
+// it, like a rewrite with code 200 or a custom 404 page in a
+// _redirects file.
+func servesTarget(code, target string) bool {
+	if target == "" {
+		return false
+	}
+	status, err := httpStatus(code)
+	return err == nil && (status < 300 || status > 399)
+}
+
+// Handler is an http.Handler that answers requests matching the rules
+// with the status code and Location of the first redirect, and passes
+// the other requests to the next Handler. Requests matching a rule
+// that serves its destination (a rule with a destination and a code
+// other than 3xx, such as a rewrite with code 200) are passed to the
+// next Handler with the path of the destination, and answered with
+// the code of the rule.
+type Handler struct {
+	// How often to check whether the rules file has changed, for
+	// a Handler created with NewFileHandler
+	ReloadInterval time.Duration
+	// Called after each attempt to reload the rules file, with the
+	// error if it could not be read. The old rules are kept when
+	// there is an error. It may call the methods of the Handler.
+	OnReload func(filename string, err error)
+
+	next     http.Handler
+	settings Settings
+	filename string
+	format   string
+
+	mu      sync.RWMutex
+	rules   *RuleSet
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// NewHandler creates a Handler that applies the rules to the requests
+// before passing them to next.
+func NewHandler(rules *RuleSet, next http.Handler, settings Settings) *Handler {
+	return &Handler{
+		next:     next,
+		settings: settings,
+		rules:    rules,
+	}
+}
+
+// NewFileHandler creates a Handler that applies the rules in the file,
+// read with ParseRulesFile in the given format ("" to choose it from
+// the file), to the requests before passing them to next, reading the
+// file again when it changes.
+func NewFileHandler(filename string, format string, next http.Handler, settings Settings) (*Handler, error) {
+	h := &Handler{
+		next:     next,
+		settings: settings,
+		filename: filename,
+		format:   format,
+	}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Rules returns the RuleSet currently being applied
+func (h *Handler) Rules() *RuleSet {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.rules
+}
+
+// Reload reads the rules file again, keeping the old rules if there
+// is an error.
+func (h *Handler) Reload() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.reload()
+}
+
+// reload implements Reload, with the lock held
+func (h *Handler) reload() error {
+	h.checked = time.Now()
+	info, err := os.Stat(h.filename)
+	if err != nil {
+		return err
+	}
+	rules, err := ParseRulesFile(h.filename, h.format)
+	if err != nil {
+		return err
+	}
+	h.rules = rules
+	h.modTime = info.ModTime()
+	h.size = info.Size()
+	return nil
+}
+
+// reloadInterval returns how often to check the rules file
+func (h *Handler) reloadInterval() time.Duration {
+	if h.ReloadInterval > 0 {
+		return h.ReloadInterval
+	}
+	return DefaultReloadInterval
+}
+
+// current returns the rules to apply to a request, reading the rules
+// file again first if it is time to check it and it has changed.
+func (h *Handler) current() *RuleSet {
+	h.mu.RLock()
+	rules := h.rules
+	due := h.filename != "" && time.Since(h.checked) >= h.reloadInterval()
+	h.mu.RUnlock()
+	if !due {
+		return rules
+	}
+
+	h.mu.Lock()
+	// Another request may have checked while waiting for the
+	// lock.
+	if time.Since(h.checked) < h.reloadInterval() {
+		rules = h.rules
+		h.mu.Unlock()
+		return rules
+	}
+	h.checked = time.Now()
+	info, err := os.Stat(h.filename)
+	if err == nil && info.ModTime().Equal(h.modTime) && info.Size() == h.size {
+		rules = h.rules
+		h.mu.Unlock()
+		return rules
+	}
+	if err == nil {
+		// Remember the change even if the file cannot be
+		// parsed, so the error is only reported once.
+		h.modTime = info.ModTime()
+		h.size = info.Size()
+		err = h.reload()
+	}
+	rules = h.rules
+	h.mu.Unlock()
+
+	// Call OnReload without the lock, so it can use the Handler.
+	if h.OnReload != nil {
+		h.OnReload(h.filename, err)
+	}
+	return rules
+}
+
+// location returns the value of the Location header for the match,
+// adding the query string of the request unless the destination has
+// its own.
+func location(m *Match, req *http.Request) string {
+	if req.URL.RawQuery == "" || strings.Contains(m.Match, "?") {
+		return m.Match
+	}
+	return m.Match + "?" + req.URL.RawQuery
+}
+
+// statusWriter is an http.ResponseWriter that answers with the code of
+// the rule instead of 200, for a rule that serves another path with
+// its own status. Other codes from the next Handler, such as a 404
+// for a missing page, are kept.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusOK {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.ResponseWriter.Write(b)
+}
+
+// rewrite passes the request to the next Handler with the path
+// replaced by the destination of the rule, the way a rule with code
+// 200 serves another path without redirecting, and answers with the
+// status of the rule.
+func (h *Handler) rewrite(w http.ResponseWriter, req *http.Request, m *Match, status int) {
+	dest, err := url.Parse(location(m, req))
+	if err != nil || dest.IsAbs() {
+		http.Error(w, fmt.Sprintf("Could not rewrite to %q", m.Match),
+			http.StatusInternalServerError)
+		return
+	}
+	r := req.Clone(req.Context())
+	r.URL.Path = dest.Path
+	r.URL.RawPath = dest.RawPath
+	r.URL.RawQuery = dest.RawQuery
+	r.RequestURI = r.URL.RequestURI()
+	if status != http.StatusOK {
+		w = &statusWriter{ResponseWriter: w, status: status}
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m := h.current().Lookup(req.URL.EscapedPath(), h.settings)
+	if m == nil {
+		h.next.ServeHTTP(w, req)
+		return
+	}
+
+	status, err := httpStatus(m.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if servesTarget(m.Code, m.Match) {
+		h.rewrite(w, req, m, status)
+		return
+	}
+	if m.Match == "" {
+		// a redirect that doesn't point to a path, like code
+		// 410
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.Header().Set("Location", location(m, req))
+	w.WriteHeader(status)
+}
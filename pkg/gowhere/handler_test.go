@@ -0,0 +1,157 @@
+package gowhere
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// okHandler answers every request with "ok"
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func TestHandler(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 302 /q /r?x=1
+redirect 410 /gone
+redirect temp /t /u
+redirectmatch 301 ^/docs/(.*)$ /new/$1
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	h := NewHandler(rs, okHandler, Settings{})
+
+	var tests = []struct {
+		target   string
+		status   int
+		location string
+		body     string
+	}{
+		{"/a", 301, "/b", ""},
+		{"/a?page=2", 301, "/b?page=2", ""},
+		{"/q?page=2", 302, "/r?x=1", ""},
+		{"/gone", 410, "", "Gone\n"},
+		{"/t", 302, "/u", ""},
+		{"/docs/x%20y", 301, "/new/x%20y", ""},
+		{"/other", 200, "", "ok"},
+	}
+
+	for n, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Code != test.status {
+			t.Errorf("test %d: got status %d expected %d", n, w.Code, test.status)
+		}
+		if loc := w.Header().Get("Location"); loc != test.location {
+			t.Errorf("test %d: got location %q expected %q", n, loc, test.location)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("test %d: got body %q expected %q", n, w.Body.String(), test.body)
+		}
+	}
+}
+
+func TestFileHandlerReload(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "htaccess")
+	if err := os.WriteFile(filename, []byte("redirect 301 /a /b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewFileHandler(filename, "", okHandler, Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.ReloadInterval = time.Nanosecond
+	var reloadErrors []error
+	h.OnReload = func(filename string, err error) {
+		// The callback can use the Handler.
+		if h.Rules() == nil {
+			t.Errorf("got no rules in OnReload")
+		}
+		reloadErrors = append(reloadErrors, err)
+	}
+
+	get := func() string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/a", nil))
+		return w.Header().Get("Location")
+	}
+
+	if loc := get(); loc != "/b" {
+		t.Errorf("got %q expected /b", loc)
+	}
+
+	// A change to the file is picked up by the next request.
+	os.WriteFile(filename, []byte("redirect 301 /a /c\n"), 0644)
+	os.Chtimes(filename, time.Now(), time.Now().Add(time.Minute))
+	if loc := get(); loc != "/c" {
+		t.Errorf("got %q expected /c after reload", loc)
+	}
+
+	// A file that cannot be parsed leaves the old rules in place
+	// and is only reported once.
+	os.WriteFile(filename, []byte("redirect\n"), 0644)
+	os.Chtimes(filename, time.Now(), time.Now().Add(2*time.Minute))
+	get()
+	if loc := get(); loc != "/c" {
+		t.Errorf("got %q expected /c after a bad reload", loc)
+	}
+	if len(reloadErrors) != 2 || reloadErrors[0] != nil || reloadErrors[1] == nil {
+		t.Errorf("got reload errors %v expected nil and an error", reloadErrors)
+	}
+}
+
+func TestNewFileHandlerMissing(t *testing.T) {
+	_, err := NewFileHandler(filepath.Join(t.TempDir(), "missing"), "", okHandler, Settings{})
+	if err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestHandlerRewrite(t *testing.T) {
+	data := `/app/* /index.html 200
+/api/:name /v2/:name?from=api 200
+/docs/* /404.html 404
+/lost/* /missing.html 404
+`
+	rs, err := ParseNetlify(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/missing.html" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(req.URL.RequestURI()))
+	})
+	h := NewHandler(rs, echo, Settings{})
+
+	var tests = []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/app/settings", 200, "/index.html"},
+		{"/app/settings?tab=2", 200, "/index.html?tab=2"},
+		{"/api/users", 200, "/v2/users?from=api"},
+		{"/other", 200, "/other"},
+		{"/docs/old", 404, "/404.html"},
+		{"/lost/page", 404, "not found\n"},
+	}
+
+	for n, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Code != test.status {
+			t.Errorf("test %d: got status %d expected %d", n, w.Code, test.status)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("test %d: got body %q expected %q", n, w.Body.String(), test.body)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gowhere
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// htaccessEscaper escapes the whitespace in the paths and targets of
+// rules, which would otherwise split the fields of the directive
+var htaccessEscaper = strings.NewReplacer(" ", "%20", "\t", "%09")
+
+// htaccessRegexpEscaper escapes the whitespace in regexp patterns
+var htaccessRegexpEscaper = strings.NewReplacer(" ", `\x20`, "\t", `\t`)
+
+// WriteHtaccess writes the rules as Apache redirect and redirectmatch
+// directives, one per line in the order they are applied, with
+// numeric status codes. Rules from other formats are converted to the
+// equivalent directive. The warnings describe the rules that cannot be
+// converted, such as rewrites that do not redirect, and the rules that
+// may be applied in a different order because the original format
+// does not always use the first matching rule.
+func WriteHtaccess(out io.Writer, rules *RuleSet) ([]ConversionWarning, error) {
+	var warnings conversionWarnings
+
+	overlapping(rules, func(first, second int, path string) {
+		a, b := &rules.rules[first], &rules.rules[second]
+		if m := rules.Lookup(path, Settings{}); m != nil && m.LineNum == b.LineNum {
+			warnings.add(*a, "applied before %s to paths such as %s", b.String(), path)
+		}
+	})
+
+	droppedRewrites(rules, &warnings)
+
+	for i := range rules.rules {
+		r := &rules.rules[i]
+		code, err := httpStatus(r.Code)
+		if err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		}
+		if err := nginxTargetError(rules, r); err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		}
+		if servesTarget(r.Code, r.Target) {
+			warnings.add(*r, "cannot convert: rewrites serving another path with status %d are not supported",
+				code)
+			continue
+		}
+		if r.Directive == "netlify" {
+			converted := r.redirectMatch()
+			r = &converted
+		}
+
+		fields := []string{r.Directive, strconv.Itoa(code)}
+		switch r.Directive {
+		case "redirect":
+			fields = append(fields, htaccessEscaper.Replace(r.Pattern))
+		case "redirectmatch":
+			fields = append(fields, htaccessRegexpEscaper.Replace(r.Pattern))
+		default:
+			warnings.add(*r, "cannot convert: unknown directive %q", r.Directive)
+			continue
+		}
+		if r.Target != "" {
+			fields = append(fields, htaccessEscaper.Replace(r.Target))
+		} else if code != 410 {
+			warnings.add(*r, "cannot convert: status %d needs a target", code)
+			continue
+		}
+
+		if _, err := fmt.Fprintln(out, strings.Join(fields, " ")); err != nil {
+			return warnings.sorted(), err
+		}
+	}
+	return warnings.sorted(), nil
+}
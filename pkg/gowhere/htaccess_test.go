@@ -0,0 +1,138 @@
+package gowhere
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteHtaccess(t *testing.T) {
+	data := `source,target,code,type
+/a,/b,permanent,literal
+^/docs/(.*)$,/new/$1,302,regex
+/gone,,410,literal
+/with space,/c d,307,literal
+^/x y$,/z,301,regex
+`
+	rs, err := ParseCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	warnings, err := WriteHtaccess(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `redirect 301 /a /b
+redirectmatch 302 ^/docs/(.*)$ /new/$1
+redirect 410 /gone
+redirect 307 /with%20space /c%20d
+redirectmatch 301 ^/x\x20y$ /z
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("got warnings %v", warnings)
+	}
+}
+
+// Rules from other formats pass the checks generated for the original
+// rules once they are converted.
+func TestWriteHtaccessRoundTrip(t *testing.T) {
+	var tests = []struct {
+		name  string
+		parse func(string) (*RuleSet, error)
+		data  string
+	}{
+		{"netlify", func(s string) (*RuleSet, error) { return ParseNetlify(strings.NewReader(s)) },
+			"/news/* /blog/:splat\n/blog/:year/:slug /posts/:slug-:year 302\n/home /\n"},
+		{"nginx", func(s string) (*RuleSet, error) { return ParseNginx(strings.NewReader(s)) },
+			"location = /a { return 301 /b; }\nrewrite ^/docs/(.*)$ /new/$1 permanent;\n"},
+		{"csv", func(s string) (*RuleSet, error) { return ParseCSV(strings.NewReader(s)) },
+			"/a,/b\n^/docs/(.*)$,/new/$1,302,regex\n/with space,/c\n"},
+	}
+
+	for n, test := range tests {
+		rs, err := test.parse(test.data)
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		var out bytes.Buffer
+		warnings, err := WriteHtaccess(&out, rs)
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		if len(warnings) != 0 {
+			t.Errorf("test %d: got warnings %v", n, warnings)
+		}
+		converted, err := ParseRules(&out)
+		if err != nil {
+			t.Errorf("test %d: %v in:\n%s", n, err, out.String())
+			continue
+		}
+
+		var checks []Check
+		for _, g := range GenerateChecks(rs, Settings{}) {
+			if g.Check != nil {
+				checks = append(checks, *g.Check)
+			}
+		}
+		if len(checks) == 0 {
+			t.Errorf("test %d: no checks generated", n)
+			continue
+		}
+		results := ProcessChecks(converted, checks, Settings{})
+		if len(results.Mismatched) != 0 || len(results.Unmatched) != 0 {
+			t.Errorf("test %d: got mismatched %v unmatched %v", n, results.Mismatched, results.Unmatched)
+		}
+	}
+}
+
+func TestWriteHtaccessWarnings(t *testing.T) {
+	nginx := func(s string) (*RuleSet, error) { return ParseNginx(strings.NewReader(s)) }
+	var tests = []struct {
+		parse   func(string) (*RuleSet, error)
+		data    string
+		lines   []int
+		message string
+	}{
+		// nginx uses the exact location before the regular
+		// expression location, even though it comes later in
+		// the file.
+		{nginx, "location ~ ^/docs/ { return 301 /new; }\nlocation = /docs/a { return 302 /elsewhere; }\n",
+			[]int{1}, "applied before"},
+		{func(s string) (*RuleSet, error) { return ParseNetlify(strings.NewReader(s)) },
+			"/app/* /index.html 200\n", []int{1}, "rewrites"},
+		{nginx, "location = /u { return 301 /x$uri; }\n", []int{1}, "$uri"},
+		{nginx, "location = /u { return 301 https://example.com$request_uri; }\n", []int{1}, "$request_uri"},
+		{nginx, "rewrite ^/old/(.*)$ /new/$1 last;\nlocation = /new/a { return 301 /b; }\n",
+			[]int{1}, "internal rewrite"},
+	}
+
+	for n, test := range tests {
+		rs, err := test.parse(test.data)
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		var out bytes.Buffer
+		warnings, err := WriteHtaccess(&out, rs)
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		var lines []int
+		for _, w := range warnings {
+			lines = append(lines, w.Rule.LineNum)
+		}
+		if !reflect.DeepEqual(lines, test.lines) ||
+			!strings.Contains(warnings[0].Message, test.message) {
+			t.Errorf("test %d: got warnings %v expected lines %v with %q",
+				n, warnings, test.lines, test.message)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package gowhere
+
+import (
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// ruleIndex narrows down the rules that could match a path, so
+// firstMatch does not have to try every rule in a large RuleSet.
+type ruleIndex struct {
+	// rules with literal patterns, by decoded pattern
+	literal map[string][]int
+	// regexp rules anchored to the start of the path, by the
+	// literal prefix of the pattern
+	prefixes *prefixNode
+	// other regexp rules, by the literal text their patterns
+	// require
+	required *prefilter
+	// rules that have to be tried for every path
+	always []int
+}
+
+// prefixNode is one node of the trie holding the literal prefixes of
+// anchored regexp rules.
+type prefixNode struct {
+	rules    []int
+	children map[byte]*prefixNode
+}
+
+// add stores the rule index in the trie under the prefix
+func (n *prefixNode) add(prefix string, rule int) {
+	for i := 0; i < len(prefix); i++ {
+		if n.children == nil {
+			n.children = make(map[byte]*prefixNode)
+		}
+		child, ok := n.children[prefix[i]]
+		if !ok {
+			child = &prefixNode{}
+			n.children[prefix[i]] = child
+		}
+		n = child
+	}
+	n.rules = append(n.rules, rule)
+}
+
+// collect appends the rules with a prefix of path to candidates
+func (n *prefixNode) collect(path string, candidates []int) []int {
+	candidates = append(candidates, n.rules...)
+	for i := 0; i < len(path); i++ {
+		n = n.children[path[i]]
+		if n == nil {
+			break
+		}
+		candidates = append(candidates, n.rules...)
+	}
+	return candidates
+}
+
+// newRuleIndex builds the index for the rules
+func newRuleIndex(rules []Rule) *ruleIndex {
+	idx := ruleIndex{
+		literal:  make(map[string][]int),
+		prefixes: &prefixNode{},
+		required: newPrefilter(),
+	}
+
+	for i, r := range rules {
+		switch r.Directive {
+		case "redirect":
+			key := DecodePath(r.Pattern)
+			idx.literal[key] = append(idx.literal[key], i)
+			continue
+		case "redirectmatch":
+			if prefix, ok := anchoredPrefix(r.Pattern); ok {
+				idx.prefixes.add(prefix, i)
+				continue
+			}
+			if literal := requiredLiteral(r.Pattern); literal != "" {
+				idx.required.add(literal, i)
+				continue
+			}
+		case "netlify":
+			if prefix, ok := anchoredPrefix(r.re.String()); ok {
+				idx.prefixes.add(prefix, i)
+				continue
+			}
+		}
+		idx.always = append(idx.always, i)
+	}
+	idx.required.build()
+
+	return &idx
+}
+
+// candidates returns the positions of the rules that could match the
+// path, in the order they appear in the RuleSet.
+func (idx *ruleIndex) candidates(path string) []int {
+	path = DecodePath(path)
+
+	var candidates []int
+	candidates = append(candidates, idx.literal[path]...)
+	candidates = idx.prefixes.collect(path, candidates)
+	candidates = idx.required.collect(path, candidates)
+	candidates = append(candidates, idx.always...)
+	sort.Ints(candidates)
+	return candidates
+}
+
+// anchoredPrefix returns the literal text any path matching the
+// regexp must begin with, if the regexp is anchored to the start of
+// the path.
+func anchoredPrefix(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	re = re.Simplify()
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	if len(subs) == 0 || subs[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+
+	var prefix strings.Builder
+	for _, sub := range subs[1:] {
+		if !literalPrefix(sub, &prefix) {
+			break
+		}
+	}
+	return prefix.String(), true
+}
+
+// literalPrefix adds the literal text at the start of the regexp to
+// prefix, and reports whether the regexp is entirely literal.
+func literalPrefix(re *syntax.Regexp, prefix *strings.Builder) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return false
+		}
+		prefix.WriteString(string(re.Rune))
+		return true
+	case syntax.OpCapture:
+		return literalPrefix(re.Sub[0], prefix)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !literalPrefix(sub, prefix) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpEmptyMatch:
+		return true
+	}
+	return false
+}
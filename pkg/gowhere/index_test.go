@@ -0,0 +1,115 @@
+package gowhere
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestAnchoredPrefix(t *testing.T) {
+	var tests = []struct {
+		pattern  string
+		prefix   string
+		anchored bool
+	}{
+		{"^/project/([^/]+)/old_page.html$", "/project/", true},
+		{"^/renamed/old/", "/renamed/old/", true},
+		{"^/(docs)/(.*)$", "/docs/", true},
+		{"^/$", "/", true},
+		{"^(.*)$", "", true},
+		{"^/(?i)Docs/", "/", true},
+		{"/renamed/old/", "", false},
+		{"^/a|^/b", "", false},
+		{"(?m)^/a", "", false},
+	}
+
+	for n, test := range tests {
+		prefix, anchored := anchoredPrefix(test.pattern)
+		if prefix != test.prefix || anchored != test.anchored {
+			t.Errorf("test %d: anchoredPrefix(%q) == %q, %v, expected %q, %v",
+				n, test.pattern, prefix, anchored,
+				test.prefix, test.anchored)
+		}
+	}
+}
+
+func TestIndexPreservesRuleOrder(t *testing.T) {
+	data := []byte(`redirectmatch 301 /any/(.*)$ /unanchored/$1
+redirect 301 /docs/a /literal/a
+redirectmatch 301 ^/docs/(.*)$ /prefix/$1
+redirectmatch 301 ^/(.*)$ /everything/$1
+redirect 301 /docs/b /literal/b
+`)
+	rs, err := ParseRules(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	linear := RuleSet{rules: rs.rules}
+
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"/x/any/page", "/unanchored/page"},
+		{"/docs/a", "/literal/a"},
+		{"/docs/b", "/prefix/b"},
+		{"/other", "/everything/other"},
+	}
+
+	for n, test := range tests {
+		m := rs.firstMatch(test.input, Settings{})
+		if m == nil || m.Match != test.want {
+			t.Errorf("test %d: got %v expected %s", n, m, test.want)
+			continue
+		}
+		l := linear.firstMatch(test.input, Settings{})
+		if l == nil || l.LineNum != m.LineNum {
+			t.Errorf("test %d: indexed match %v differs from linear match %v",
+				n, m, l)
+		}
+	}
+}
+
+// generateRules creates a large htaccess file similar to the ones
+// generated for documentation sites.
+func generateRules(n int) *RuleSet {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		switch {
+		case i%20 == 0:
+			fmt.Fprintf(&buf, "redirectmatch 301 /legacy%d/(.*)$ /new%d/$1\n", i, i)
+		case i%5 == 0:
+			fmt.Fprintf(&buf, "redirectmatch 301 ^/project%d/([^/]+)/old.html$ /project%d/$1/new.html\n", i, i)
+		default:
+			fmt.Fprintf(&buf, "redirect 301 /section%d/page.html /moved%d/page.html\n", i, i)
+		}
+	}
+	rs, _ := ParseRules(&buf)
+	return rs
+}
+
+var benchmarkInputs = []string{
+	"/section24998/page.html",
+	"/project24995/abc/old.html",
+	"/nothing/matches/this.html",
+}
+
+func BenchmarkFirstMatchIndexed(b *testing.B) {
+	rs := generateRules(25000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, input := range benchmarkInputs {
+			rs.firstMatch(input, Settings{})
+		}
+	}
+}
+
+func BenchmarkFirstMatchLinear(b *testing.B) {
+	rs := RuleSet{rules: generateRules(25000).rules}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, input := range benchmarkInputs {
+			rs.firstMatch(input, Settings{})
+		}
+	}
+}
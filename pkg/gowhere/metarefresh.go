@@ -0,0 +1,105 @@
+package gowhere
+
+import (
+	"bytes"
+	"html/template"
+	"path"
+	"strings"
+)
+
+// RedirectPage is an HTML page that sends the browser to the
+// destination of a redirect, for hosts that can only serve files
+type RedirectPage struct {
+	// The rule that redirects the path
+	Rule Rule
+	// The name of the file to serve for the path, relative to the
+	// root of the site
+	Filename string
+	// The contents of the file
+	Content []byte
+}
+
+var redirectPageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Redirecting to {{.}}</title>
+<link rel="canonical" href="{{.}}">
+<meta http-equiv="refresh" content="0; url={{.}}">
+<meta name="robots" content="noindex">
+</head>
+<body>
+<p>This page has moved to <a href="{{.}}">{{.}}</a>.</p>
+</body>
+</html>
+`))
+
+// redirectPageFilename returns the name of the file a static host
+// serves for the path: the path itself if it names a file, or the
+// index.html file in the directory if it has no extension or ends in
+// a slash
+func redirectPageFilename(p string) string {
+	dir := strings.HasSuffix(p, "/")
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if dir || p == "" || path.Ext(p) == "" {
+		return path.Join(p, "index.html")
+	}
+	return p
+}
+
+// RedirectPages returns an HTML page for each rule with a literal
+// path, using a meta refresh tag and a canonical link to send browsers
+// and search engines to the destination. The pages cannot set the
+// status code, so every redirect looks the same. The warnings describe
+// the rules that cannot be converted, such as regular expressions and
+// rules that do not redirect, and the rules that are never used
+// because an earlier rule handles the path or writes the same page.
+func RedirectPages(rules *RuleSet) ([]RedirectPage, []ConversionWarning) {
+	var pages []RedirectPage
+	var warnings conversionWarnings
+
+	droppedRewrites(rules, &warnings)
+
+	files := make(map[string]int)
+	for i := range rules.rules {
+		r := &rules.rules[i]
+		if err := nginxTargetError(rules, r); err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		}
+		p, ok := r.literalPath()
+		if !ok {
+			warnings.add(*r, "cannot convert: a page can only be written for a literal path")
+			continue
+		}
+		if code, err := httpStatus(r.Code); err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		} else if code < 300 || code > 399 || r.Target == "" {
+			warnings.add(*r, "cannot convert: a page can only redirect, not return status %d", code)
+			continue
+		}
+
+		if m := rules.Lookup(EncodePath(p), Settings{}); m != nil && m.LineNum != r.LineNum {
+			warnings.add(*r, "never used, %s handles the path first", m.Rule.String())
+			continue
+		}
+
+		filename := redirectPageFilename(p)
+		if line, ok := files[filename]; ok {
+			warnings.add(*r, "never used, the rule on line %d writes the page %s", line, filename)
+			continue
+		}
+		files[filename] = r.LineNum
+
+		var content bytes.Buffer
+		target := r.Explain(EncodePath(p), Settings{}).Match
+		if err := redirectPageTemplate.Execute(&content, target); err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		}
+		pages = append(pages, RedirectPage{Rule: *r, Filename: filename,
+			Content: content.Bytes()})
+	}
+	return pages, warnings.sorted()
+}
@@ -0,0 +1,68 @@
+package gowhere
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPageFilename(t *testing.T) {
+	var tests = []struct {
+		path     string
+		expected string
+	}{
+		{"/", "index.html"},
+		{"/a", "a/index.html"},
+		{"/a/", "a/index.html"},
+		{"/a/b.html", "a/b.html"},
+		{"/v1.2/", "v1.2/index.html"},
+		{"/../a.html", "a.html"},
+	}
+
+	for n, test := range tests {
+		if actual := redirectPageFilename(test.path); actual != test.expected {
+			t.Errorf("test %d: got %q expected %q", n, actual, test.expected)
+		}
+	}
+}
+
+func TestRedirectPages(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirectmatch 302 ^/docs/(.*)$ /new/$1
+redirect 301 /docs/x /never
+redirect 410 /gone
+redirectmatch 301 ^/old\.html$ /new.html?from=old
+redirect 301 /a/ /c
+redirect 301 /with%20space /b&c
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	pages, warnings := RedirectPages(rs)
+
+	var filenames []string
+	for _, p := range pages {
+		filenames = append(filenames, p.Filename)
+	}
+	if strings.Join(filenames, " ") != "a/index.html old.html with space/index.html" {
+		t.Errorf("got pages %q", filenames)
+	}
+	var lines []int
+	for _, w := range warnings {
+		lines = append(lines, w.Rule.LineNum)
+	}
+	if len(lines) != 4 || lines[0] != 2 || lines[1] != 3 || lines[2] != 4 || lines[3] != 6 {
+		t.Errorf("got warnings %v expected lines 2, 3, 4, and 6", warnings)
+	}
+
+	content := string(pages[1].Content)
+	for _, expected := range []string{
+		`<link rel="canonical" href="/new.html?from=old">`,
+		`<meta http-equiv="refresh" content="0; url=/new.html?from=old">`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %s in:\n%s", expected, content)
+		}
+	}
+	if content := string(pages[2].Content); !strings.Contains(content, `href="/b&amp;c"`) {
+		t.Errorf("expected the destination to be escaped in:\n%s", content)
+	}
+}
@@ -0,0 +1,180 @@
+package gowhere
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// netlifyPlaceholderRE matches a :placeholder in the path or
+// destination of a _redirects rule
+var netlifyPlaceholderRE = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// netlifyCodeRE matches the status of a _redirects rule, with the
+// optional force flag
+var netlifyCodeRE = regexp.MustCompile(`^([0-9]{3})(!?)$`)
+
+// netlifyPattern converts the path of a _redirects rule to a regexp.
+// Each :placeholder matches one path segment, a trailing * matches
+// the rest of the path as the "splat", and a trailing slash is
+// optional.
+func netlifyPattern(path string) string {
+	splat := strings.HasSuffix(path, "*")
+	path = strings.TrimSuffix(path, "*")
+	path = strings.TrimSuffix(path, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, m := range netlifyPlaceholderRE.FindAllStringSubmatchIndex(path, -1) {
+		b.WriteString(regexp.QuoteMeta(path[last:m[0]]))
+		fmt.Fprintf(&b, "(?P<%s>[^/]+)", path[m[2]:m[3]])
+		last = m[1]
+	}
+	b.WriteString(regexp.QuoteMeta(path[last:]))
+	if splat {
+		b.WriteString("(?:/(?P<splat>.*))?$")
+	} else {
+		b.WriteString("/?$")
+	}
+	return b.String()
+}
+
+// netlifyTemplate converts the destination of a _redirects rule to a
+// template for regexp.Expand, replacing :splat and the placeholders
+// that appear in the pattern with references to the groups.
+func netlifyTemplate(re *regexp.Regexp, target string) string {
+	names := make(map[string]bool)
+	for _, n := range re.SubexpNames() {
+		if n != "" {
+			names[n] = true
+		}
+	}
+	target = strings.ReplaceAll(target, "$", "$$")
+	return netlifyPlaceholderRE.ReplaceAllStringFunc(target, func(p string) string {
+		if names[p[1:]] {
+			return "${" + p[1:] + "}"
+		}
+		return p
+	})
+}
+
+// isNetlifyQuery tests whether a field of a _redirects rule is a
+// query parameter to match (such as "id=:id") rather than the
+// destination
+func isNetlifyQuery(field string) bool {
+	return strings.Contains(field, "=") && !strings.HasPrefix(field, "/") &&
+		!strings.Contains(field, "://")
+}
+
+// redirectMatch returns an equivalent "redirectmatch" rule for a rule
+// from a _redirects file, with the placeholders in the destination
+// replaced by numbered group references, so it can be written in
+// formats without placeholders.
+func (r *Rule) redirectMatch() Rule {
+	groups := make(map[string]int)
+	for i, n := range r.re.SubexpNames() {
+		if n != "" {
+			groups[n] = i
+		}
+	}
+	target := netlifyPlaceholderRE.ReplaceAllStringFunc(r.Target, func(p string) string {
+		if i, ok := groups[p[1:]]; ok {
+			return fmt.Sprintf("$%d", i)
+		}
+		return p
+	})
+	return Rule{LineNum: r.LineNum, Directive: "redirectmatch", Code: r.Code,
+		Pattern: r.re.String(), Target: target, re: r.re}
+}
+
+// newNetlifyRule creates a Rule for one line of a _redirects file
+func newNetlifyRule(lineNum int, from, to, code string) (*Rule, error) {
+	if !strings.HasPrefix(from, "/") {
+		return nil, fmt.Errorf("Could not understand path '%s' on line %d", from, lineNum)
+	}
+	if i := strings.Index(from, "*"); i >= 0 && i != len(from)-1 {
+		return nil, fmt.Errorf("Splat must be at the end of path '%s' on line %d",
+			from, lineNum)
+	}
+	re, err := regexp.Compile(netlifyPattern(from))
+	if err != nil {
+		return nil, fmt.Errorf("Could not understand path '%s' on line %d: %v",
+			from, lineNum, err)
+	}
+	return &Rule{LineNum: lineNum, Directive: "netlify", Code: code,
+		Pattern: from, Target: to, re: re}, nil
+}
+
+// parseRedirectsFile reads a _redirects file, using the default code
+// for rules without one
+func parseRedirectsFile(fd io.Reader, defaultCode string) (*RuleSet, error) {
+	var rules []Rule
+	lineNum := 0
+	input := bufio.NewScanner(fd)
+	for input.Scan() {
+		line := strings.Trim(input.Text(), " \t\r\n")
+		lineNum++
+
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return NewRuleSet(rules), fmt.Errorf("Not enough parameters on line %d: %v",
+				lineNum, fields)
+		}
+		if isNetlifyQuery(fields[1]) {
+			return NewRuleSet(rules), fmt.Errorf(
+				"Query parameter matching on line %d is not supported: %v",
+				lineNum, fields)
+		}
+		code := defaultCode
+		if len(fields) >= 3 {
+			m := netlifyCodeRE.FindStringSubmatch(fields[2])
+			if m == nil {
+				return NewRuleSet(rules), fmt.Errorf(
+					"Could not understand status '%s' on line %d", fields[2], lineNum)
+			}
+			// The force flag makes the rule apply even if
+			// there is a file at the path. gowhere does not
+			// know about the files, so every rule is treated
+			// as forced.
+			code = m[1]
+		}
+		if len(fields) > 3 {
+			return NewRuleSet(rules), fmt.Errorf(
+				"Conditions on line %d are not supported: %v", lineNum, fields[3:])
+		}
+
+		r, err := newNetlifyRule(lineNum, fields[0], fields[1], code)
+		if err != nil {
+			return NewRuleSet(rules), err
+		}
+		rules = append(rules, *r)
+	}
+	return NewRuleSet(rules), input.Err()
+}
+
+// ParseNetlify reads the rules from a Netlify _redirects file and
+// returns a RuleSet containing all of them, applied in order with the
+// first match winning. Rules without a status use 301. A :placeholder
+// in the path matches one path segment, a * at the end of the path
+// matches the rest of the path, and both may be used in the
+// destination (as :placeholder and :splat). A trailing slash on the
+// path is optional. Rules with status 200 are rewrites, which serve the
+// destination without redirecting and so end the redirect chain.
+// Stops on the first error parsing the file.
+func ParseNetlify(fd io.Reader) (*RuleSet, error) {
+	return parseRedirectsFile(fd, "301")
+}
+
+// ParseCloudflare reads the rules from a Cloudflare Pages _redirects
+// file, which uses the same format as ParseNetlify except that rules
+// without a status use 302.
+func ParseCloudflare(fd io.Reader) (*RuleSet, error) {
+	return parseRedirectsFile(fd, "302")
+}
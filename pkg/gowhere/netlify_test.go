@@ -0,0 +1,207 @@
+package gowhere
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNetlifyPattern(t *testing.T) {
+	var tests = []struct {
+		path     string
+		expected string
+	}{
+		{"/a", "^/a/?$"},
+		{"/a/", "^/a/?$"},
+		{"/a.html", `^/a\.html/?$`},
+		{"/news/*", "^/news(?:/(?P<splat>.*))?$"},
+		{"/*", "^(?:/(?P<splat>.*))?$"},
+		{"/blog/:year/:slug", "^/blog/(?P<year>[^/]+)/(?P<slug>[^/]+)/?$"},
+	}
+
+	for n, test := range tests {
+		if actual := netlifyPattern(test.path); actual != test.expected {
+			t.Errorf("test %d: got %q expected %q", n, actual, test.expected)
+		}
+	}
+}
+
+func TestParseNetlify(t *testing.T) {
+	data := `# comment
+/home              /
+/news/*            /blog/:splat
+/blog/:year/:slug  /posts/:slug?year=:year  302
+/app/*             /index.html              200!
+/gone              /                        410
+/:other/x          https://example.com/:missing
+`
+	rs, err := ParseNetlify(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, r := range rs.rules {
+		actual = append(actual, r.String())
+	}
+	expected := []string{
+		"[line 2] netlify /home 301 /",
+		"[line 3] netlify /news/* 301 /blog/:splat",
+		"[line 4] netlify /blog/:year/:slug 302 /posts/:slug?year=:year",
+		"[line 5] netlify /app/* 200 /index.html",
+		"[line 6] netlify /gone 410 /",
+		"[line 7] netlify /:other/x 301 https://example.com/:missing",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q expected %q", actual, expected)
+	}
+
+	var tests = []struct {
+		input    string
+		expected string
+	}{
+		{"/home", "/"},
+		{"/home/", "/"},
+		{"/homes", ""},
+		{"/news/2020/x", "/blog/2020/x"},
+		{"/news", "/blog/"},
+		{"/blog/2020/hello", "/posts/hello?year=2020"},
+		{"/blog/2020/hello/more", ""},
+		{"/app/settings", "/index.html"},
+		{"/y/x", "https://example.com/:missing"},
+	}
+
+	for n, test := range tests {
+		actual := ""
+		if m := rs.Lookup(test.input, Settings{}); m != nil {
+			actual = m.Match
+		}
+		if actual != test.expected {
+			t.Errorf("test %d: got %q expected %q", n, actual, test.expected)
+		}
+	}
+}
+
+func TestParseCloudflare(t *testing.T) {
+	rs, err := ParseCloudflare(strings.NewReader("/a /b\n/c /d 301\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"302", "301"} {
+		if code := rs.rules[i].Code; code != expected {
+			t.Errorf("rule %d: got code %s expected %s", i, code, expected)
+		}
+	}
+}
+
+func TestParseNetlifyErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		data string
+	}{
+		{"not enough parameters", "/a\n"},
+		{"relative path", "a /b\n"},
+		{"splat in middle", "/a/*/b /c\n"},
+		{"bad status", "/a /b moved\n"},
+		{"query parameters", "/a id=:id /b 301\n"},
+		{"conditions", "/a /b 302 Country=us\n"},
+	}
+
+	for n, test := range tests {
+		if _, err := ParseNetlify(strings.NewReader(test.data)); err == nil {
+			t.Errorf("test %d: expected an error", n)
+		}
+	}
+}
+
+func TestNetlifyRewriteEndsChain(t *testing.T) {
+	data := `/old /app/old
+/app/* /index.html 200
+/index.html /elsewhere
+/missing/* /404.html 404
+/404.html /elsewhere
+/gone /index.html 410
+`
+	rs, err := ParseNetlify(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		input    string
+		hops     int
+		code     string
+		expected string
+	}{
+		{"/old", 2, "200", "/index.html"},
+		{"/missing/page", 1, "404", "/404.html"},
+		{"/gone", 1, "410", "/index.html"},
+	}
+
+	for n, test := range tests {
+		matches, end := rs.followChain(&Check{Input: test.input}, Settings{MaxHops: 10})
+		if end != Rewritten {
+			t.Errorf("test %d: got end %v expected %v", n, end, Rewritten)
+		}
+		if len(matches) != test.hops {
+			t.Errorf("test %d: got %v expected %d hops", n, matches, test.hops)
+			continue
+		}
+		last := matches[len(matches)-1]
+		if last.Code != test.code || last.Match != test.expected {
+			t.Errorf("test %d: got %s %s expected %s %s", n,
+				last.Code, last.Match, test.code, test.expected)
+		}
+		e := rs.Explain(test.input, Settings{MaxHops: 10})
+		if e.End != Rewritten || len(e.Hops) != test.hops {
+			t.Errorf("test %d: got end %v after %d hops", n, e.End, len(e.Hops))
+		}
+	}
+}
+
+func TestNetlifySamples(t *testing.T) {
+	rs, _ := ParseNetlify(strings.NewReader("/blog/:year/:slug /posts/:slug\n/news/* /blog/:splat\n"))
+	for _, r := range rs.rules {
+		if _, ok := r.Example(); !ok {
+			t.Errorf("%s: no example", r.String())
+		}
+	}
+}
+
+// Rules from a _redirects file are converted with numbered group
+// references and pass the checks generated for the original rules.
+func TestWriteNginxNetlify(t *testing.T) {
+	data := "/news/* /blog/:splat\n/blog/:year/:slug /posts/:slug-:year 302\n/app/* /index.html 200\n"
+	rs, err := ParseNetlify(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	warnings, err := WriteNginx(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Rule.LineNum != 3 {
+		t.Errorf("got warnings %v expected one for line 3", warnings)
+	}
+	if !strings.Contains(out.String(), "/posts/$2-$1 redirect;") {
+		t.Errorf("got:\n%s", out.String())
+	}
+	converted, err := ParseNginx(&out)
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, out.String())
+	}
+
+	var checks []Check
+	for _, g := range GenerateChecks(NewRuleSet(rs.rules[:2]), Settings{}) {
+		if g.Check != nil {
+			checks = append(checks, *g.Check)
+		}
+	}
+	if len(checks) != 2 {
+		t.Fatalf("got checks %v", checks)
+	}
+	results := ProcessChecks(converted, checks, Settings{})
+	if len(results.Mismatched) != 0 || len(results.Unmatched) != 0 {
+		t.Errorf("got mismatched %v unmatched %v", results.Mismatched, results.Unmatched)
+	}
+}
@@ -0,0 +1,152 @@
+package gowhere
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// nginxPhase is the order in which nginx applies the kinds of
+// directives produced by WriteNginx, regardless of their order in the
+// file
+type nginxPhase int
+
+const (
+	// rewrite directives in the server block
+	nginxRewritePhase nginxPhase = iota
+	// location = blocks
+	nginxExactPhase
+	// location ~ blocks
+	nginxRegexpPhase
+)
+
+// nginxPhaseOf returns the phase in which the converted rule is
+// applied
+func nginxPhaseOf(r *Rule) nginxPhase {
+	if r.Directive == "redirect" {
+		return nginxExactPhase
+	}
+	code, _ := httpStatus(r.Code)
+	if _, ok := nginxRewriteFlags[code]; ok && r.Target != "" &&
+		!strings.Contains(r.Target, "$0") {
+		return nginxRewritePhase
+	}
+	return nginxRegexpPhase
+}
+
+// nginxRewriteFlags holds the flag of a rewrite directive producing
+// each response code
+var nginxRewriteFlags = map[int]string{
+	301: "permanent",
+	302: "redirect",
+}
+
+// nginxQuote quotes a value for an nginx configuration file if it
+// contains characters with special meaning
+func nginxQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\r\n;{}\"'#") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\\`, `\\\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// nginxGroupRefRE matches the group references in the target of a
+// regexp rule, which nginx expands the same way
+var nginxGroupRefRE = regexp.MustCompile(`\$[1-9]`)
+
+// hasNginxVariable tests whether nginx would treat part of the target
+// of the rule as a variable. A "$" is literal in the target of a
+// redirect rule, and in a redirectmatch rule unless it is followed by
+// the number of a group, but nginx expands "$name" as a variable and
+// has no way to escape it.
+func hasNginxVariable(r *Rule) bool {
+	target := r.Target
+	if r.Directive == "redirectmatch" {
+		target = nginxGroupRefRE.ReplaceAllString(target, "")
+	}
+	return strings.Contains(target, "$")
+}
+
+// nginxReturnDirective returns the return directive for the rule
+func nginxReturnDirective(r *Rule, code int) string {
+	if r.Target == "" {
+		return fmt.Sprintf("return %d;", code)
+	}
+	return fmt.Sprintf("return %d %s;", code, nginxQuote(r.Target))
+}
+
+// WriteNginx writes the rules as nginx configuration directives, to be
+// included in a server block. Literal rules become exact match
+// locations returning the redirect, regular expression rules with
+// codes 301 and 302 become rewrite directives, and the others become
+// regular expression locations. nginx applies the rewrite directives
+// first, then the exact locations, then the regular expression
+// locations, so the warnings describe the rules that may be applied in
+// a different order than in the htaccess file, as well as any rules
+// that cannot be converted.
+func WriteNginx(out io.Writer, rules *RuleSet) ([]ConversionWarning, error) {
+	var warnings conversionWarnings
+
+	overlapping(rules, func(first, second int, path string) {
+		a, b := &rules.rules[first], &rules.rules[second]
+		if nginxPhaseOf(b) < nginxPhaseOf(a) {
+			warnings.add(*a, "nginx applies %s first to paths such as %s",
+				b.String(), path)
+		}
+	})
+
+	exact := make(map[string]int)
+	for i := range rules.rules {
+		r := &rules.rules[i]
+		code, err := httpStatus(r.Code)
+		if err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		}
+		if servesTarget(r.Code, r.Target) {
+			warnings.add(*r, "cannot convert: rewrites serving another path with status %d are not supported",
+				code)
+			continue
+		}
+		if r.Directive == "netlify" {
+			converted := r.redirectMatch()
+			r = &converted
+		}
+		if strings.Contains(r.Target, "$0") && r.Directive == "redirectmatch" {
+			warnings.add(*r, "cannot convert: nginx has no variable for the whole match ($0)")
+			continue
+		}
+		if hasNginxVariable(r) {
+			warnings.add(*r, "cannot convert: nginx would expand the $ in the target as a variable")
+			continue
+		}
+
+		var directive string
+		switch {
+		case r.Directive == "redirect":
+			path := DecodePath(r.Pattern)
+			if line, ok := exact[path]; ok {
+				warnings.add(*r, "never used, the rule on line %d has the same pattern", line)
+				continue
+			}
+			exact[path] = r.LineNum
+			directive = fmt.Sprintf("location = %s {\n    %s\n}",
+				nginxQuote(path), nginxReturnDirective(r, code))
+		case nginxPhaseOf(r) == nginxRewritePhase:
+			directive = fmt.Sprintf("rewrite %s %s %s;", nginxQuote(r.Pattern),
+				nginxQuote(r.Target), nginxRewriteFlags[code])
+		default:
+			directive = fmt.Sprintf("location ~ %s {\n    %s\n}",
+				nginxQuote(r.Pattern), nginxReturnDirective(r, code))
+		}
+
+		_, err = fmt.Fprintf(out, "# %s\n%s\n\n", r.String(), directive)
+		if err != nil {
+			return warnings.sorted(), err
+		}
+	}
+	return warnings.sorted(), nil
+}
@@ -0,0 +1,138 @@
+package gowhere
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteNginx(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirectmatch 302 ^/docs/(.*)$ /new/$1
+redirect 410 /gone
+redirectmatch 307 ^/tmp/(.*)$ /t/$1
+redirect 301 /a /c
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	var out bytes.Buffer
+	warnings, err := WriteNginx(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# [line 1] redirect /a 301 /b
+location = /a {
+    return 301 /b;
+}
+
+# [line 2] redirectmatch ^/docs/(.*)$ 302 /new/$1
+rewrite ^/docs/(.*)$ /new/$1 redirect;
+
+# [line 3] redirect /gone 410 
+location = /gone {
+    return 410;
+}
+
+# [line 4] redirectmatch ^/tmp/(.*)$ 307 /t/$1
+location ~ ^/tmp/(.*)$ {
+    return 307 /t/$1;
+}
+
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	if len(warnings) != 1 || warnings[0].Rule.LineNum != 5 {
+		t.Errorf("got warnings %v expected one for line 5", warnings)
+	}
+}
+
+func TestWriteNginxOrderWarnings(t *testing.T) {
+	data := []byte(`redirect 301 /docs/index.html /start
+redirectmatch 301 ^/docs/(.*)$ /new/$1
+redirectmatch 410 ^/old/
+redirect 301 /old/page /page
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	var out bytes.Buffer
+	warnings, err := WriteNginx(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, w := range warnings {
+		lines = append(lines, w.Rule.LineNum)
+	}
+	// The literal rule is shadowed by the rewrite, and the regular
+	// expression location by the exact location.
+	if len(lines) != 2 || lines[0] != 1 || lines[1] != 3 {
+		t.Errorf("got warnings %v", warnings)
+	}
+	if !strings.Contains(warnings[0].Message, "[line 2]") {
+		t.Errorf("got %q expected a reference to line 2", warnings[0].Message)
+	}
+}
+
+// The converted rules pass the checks generated for the original
+// rules.
+func TestWriteNginxRoundTrip(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirectmatch 302 ^/docs/(.*)\.html$ /new/$1
+redirect 410 /gone
+redirectmatch 308 ^/(en|fr)/old /$1/new
+redirect 301 /with%20space /b
+redirectmatch 301 ^/x{2,3}$ /y
+`)
+	rs, err := ParseRules(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := WriteNginx(&out, rs); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := ParseNginx(&out)
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, out.String())
+	}
+
+	var checks []Check
+	for _, g := range GenerateChecks(rs, Settings{}) {
+		if g.Check != nil {
+			checks = append(checks, *g.Check)
+		}
+	}
+	results := ProcessChecks(converted, checks, Settings{})
+	if len(results.Mismatched) != 0 || len(results.Unmatched) != 0 {
+		t.Errorf("got mismatched %v unmatched %v", results.Mismatched, results.Unmatched)
+	}
+}
+
+func TestWriteNginxVariables(t *testing.T) {
+	var tests = []struct {
+		rule      string
+		converted bool
+	}{
+		{"redirectmatch 301 ^/docs/(.*)$ /new/$1", true},
+		{"redirect 301 /price /cost$", false},
+		{"redirect 301 /u /x$uri", false},
+		{"redirectmatch 301 ^/a/(.*)$ /b/$1?from=$host", false},
+		{"redirectmatch 301 ^/a/.*$ /b$0", false},
+	}
+
+	for n, test := range tests {
+		rs, _ := ParseRules(strings.NewReader(test.rule))
+		var out bytes.Buffer
+		warnings, err := WriteNginx(&out, rs)
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		if converted := out.Len() > 0; converted != test.converted {
+			t.Errorf("test %d: got converted %v expected %v (warnings %v)",
+				n, converted, test.converted, warnings)
+		}
+		if (len(warnings) == 0) != test.converted {
+			t.Errorf("test %d: got warnings %v", n, warnings)
+		}
+	}
+}
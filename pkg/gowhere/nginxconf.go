@@ -0,0 +1,149 @@
+package gowhere
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// nginxDirective holds one directive from an nginx configuration file,
+// with the directives in its block if it has one.
+type nginxDirective struct {
+	LineNum  int
+	Name     string
+	Args     []string
+	HasBlock bool
+	Block    []nginxDirective
+}
+
+// nginxToken holds one token from an nginx configuration file. The
+// punctuation tokens ("{", "}", and ";") are not quoted.
+type nginxToken struct {
+	lineNum int
+	text    string
+	quoted  bool
+}
+
+// nginxUnescape replaces the escape sequences nginx understands in
+// the values of directives, leaving other backslashes alone.
+var nginxUnescape = strings.NewReplacer(`\"`, `"`, `\'`, `'`, `\\`, `\`,
+	`\t`, "\t", `\r`, "\r", `\n`, "\n")
+
+// lexNginx splits an nginx configuration file into tokens
+func lexNginx(data string) ([]nginxToken, error) {
+	var tokens []nginxToken
+	lineNum := 1
+	i := 0
+	for i < len(data) {
+		c := data[i]
+		switch {
+		case c == '\n':
+			lineNum++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case c == '{' || c == '}' || c == ';':
+			tokens = append(tokens, nginxToken{lineNum, string(c), false})
+			i++
+		case c == '"' || c == '\'':
+			start := lineNum
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(data) {
+					return nil, fmt.Errorf("Unterminated string starting on line %d", start)
+				}
+				if data[i] == c {
+					i++
+					break
+				}
+				if data[i] == '\\' && i+1 < len(data) {
+					b.WriteByte(data[i])
+					i++
+				}
+				if data[i] == '\n' {
+					lineNum++
+				}
+				b.WriteByte(data[i])
+				i++
+			}
+			tokens = append(tokens, nginxToken{start, nginxUnescape.Replace(b.String()), true})
+		default:
+			var b strings.Builder
+			for i < len(data) && !strings.ContainsRune(" \t\r\n{};", rune(data[i])) {
+				if data[i] == '\\' && i+1 < len(data) {
+					b.WriteByte(data[i])
+					i++
+				}
+				b.WriteByte(data[i])
+				i++
+			}
+			tokens = append(tokens, nginxToken{lineNum, nginxUnescape.Replace(b.String()), false})
+		}
+	}
+	return tokens, nil
+}
+
+// parseNginxBlock reads directives from the tokens until the end of
+// the block or the file, returning the directives and the number of
+// tokens used.
+func parseNginxBlock(tokens []nginxToken, nested bool) ([]nginxDirective, int, error) {
+	var directives []nginxDirective
+	var current *nginxDirective
+	i := 0
+	for i < len(tokens) {
+		t := tokens[i]
+		i++
+		punctuation := !t.quoted && len(t.text) == 1 && strings.Contains("{};", t.text)
+		switch {
+		case !punctuation && current == nil:
+			current = &nginxDirective{LineNum: t.lineNum, Name: t.text}
+		case !punctuation:
+			current.Args = append(current.Args, t.text)
+		case t.text == ";" && current != nil:
+			directives = append(directives, *current)
+			current = nil
+		case t.text == "{" && current != nil:
+			block, n, err := parseNginxBlock(tokens[i:], true)
+			if err != nil {
+				return nil, 0, err
+			}
+			i += n
+			current.HasBlock = true
+			current.Block = block
+			directives = append(directives, *current)
+			current = nil
+		case t.text == "}" && current == nil && nested:
+			return directives, i, nil
+		default:
+			return nil, 0, fmt.Errorf("Unexpected %q on line %d", t.text, t.lineNum)
+		}
+	}
+	if current != nil {
+		return nil, 0, fmt.Errorf("Missing ';' after directive %s on line %d",
+			current.Name, current.LineNum)
+	}
+	if nested {
+		return nil, 0, fmt.Errorf("Missing '}' at the end of the file")
+	}
+	return directives, i, nil
+}
+
+// parseNginxConf reads the directives from an nginx configuration
+// file
+func parseNginxConf(fd io.Reader) ([]nginxDirective, error) {
+	data, err := io.ReadAll(fd)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lexNginx(string(data))
+	if err != nil {
+		return nil, err
+	}
+	directives, _, err := parseNginxBlock(tokens, false)
+	return directives, err
+}
@@ -0,0 +1,43 @@
+package gowhere
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLexNginx(t *testing.T) {
+	data := `# comment
+rewrite ^/a\.html$ /b permanent;
+location = "/with space" { return 301 '/it\'s'; }
+`
+	tokens, err := lexNginx(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, tok := range tokens {
+		actual = append(actual, tok.text)
+	}
+	expected := []string{"rewrite", `^/a\.html$`, "/b", "permanent", ";",
+		"location", "=", "/with space", "{", "return", "301", "/it's", ";", "}"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q expected %q", actual, expected)
+	}
+	if tokens[5].lineNum != 3 {
+		t.Errorf("got line %d expected 3", tokens[5].lineNum)
+	}
+}
+
+func TestParseNginxConfErrors(t *testing.T) {
+	for _, data := range []string{
+		"rewrite /a /b permanent",
+		"server { rewrite /a /b permanent;",
+		"}",
+		`location = "/a { return 410; }`,
+	} {
+		if _, err := parseNginxConf(strings.NewReader(data)); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
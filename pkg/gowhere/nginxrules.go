@@ -0,0 +1,568 @@
+package gowhere
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nginxMaxCycles is how many times nginx looks for a location again
+// after rewriting the path before giving up
+const nginxMaxCycles = 10
+
+// nginxVariableRE matches a variable in the replacement of a rewrite
+// directive or the URL of a return directive
+var nginxVariableRE = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// nginxVariables holds the variables that can be used in
+// destinations, other than the numbered captures
+var nginxVariables = map[string]bool{
+	"uri":          true,
+	"document_uri": true,
+	"request_uri":  true,
+	"args":         true,
+	"query_string": true,
+	"is_args":      true,
+}
+
+// nginxRedirectCodes holds the response codes for which the second
+// value of a return directive is a URL rather than the text of the
+// response
+var nginxRedirectCodes = map[string]bool{
+	"301": true,
+	"302": true,
+	"303": true,
+	"307": true,
+	"308": true,
+}
+
+// nginxRewriteCodes holds the response code for each flag of a
+// rewrite directive that always produces a redirect
+var nginxRewriteCodes = map[string]string{
+	"permanent": "301",
+	"redirect":  "302",
+}
+
+// nginxStep is one rewrite or return directive
+type nginxStep struct {
+	// The Rule reported when the step produces a redirect. The
+	// Code is empty for rewrites that change the path without
+	// redirecting.
+	rule Rule
+	// The flag of a rewrite directive, or "return"
+	flag string
+}
+
+// nginxLocation holds one location block
+type nginxLocation struct {
+	lineNum int
+	// "=", "^~", "~", "~*", or "" for a prefix
+	modifier string
+	path     string
+	re       *regexp.Regexp
+	steps    []nginxStep
+}
+
+// nginxServer applies the rules from an nginx server block the way
+// nginx does.
+type nginxServer struct {
+	// rewrite and return directives outside of any location
+	steps     []nginxStep
+	locations []nginxLocation
+}
+
+// nginxState holds the values used while handling one request
+type nginxState struct {
+	// the requested path
+	request string
+	// the current path, decoded, which rewrites may change
+	uri string
+	// the groups matched by the last regular expression
+	captures []string
+}
+
+// expand replaces the variables in a destination
+func (st *nginxState) expand(template string) string {
+	return nginxVariableRE.ReplaceAllStringFunc(template, func(v string) string {
+		name := nginxVariableRE.FindStringSubmatch(v)
+		n := name[1] + name[2]
+		switch n {
+		case "uri", "document_uri":
+			return st.uri
+		case "request_uri":
+			return st.request
+		case "args", "query_string", "is_args":
+			// The checks do not have query strings.
+			return ""
+		}
+		if i, err := strconv.Atoi(n); err == nil && i < len(st.captures) {
+			return st.captures[i]
+		}
+		return ""
+	})
+}
+
+// The ways a list of steps can end
+const (
+	// no step handled the request
+	nginxNext = iota
+	// a redirect was found
+	nginxRedirect
+	// the path was rewritten with the last flag or without a
+	// flag, so a new location is needed
+	nginxRestart
+	// the rewrites were stopped with the break flag
+	nginxBreak
+	// a response that is not a redirect
+	nginxStop
+)
+
+// run applies the steps to the request. Like nginx, a rewrite without
+// a flag changes the path and goes on to the next step, and the
+// location is chosen again for the new path after the last step.
+func (n *nginxServer) run(steps []nginxStep, st *nginxState, settings Settings) (*Match, int) {
+	changed := false
+	for i := range steps {
+		step := &steps[i]
+		r := &step.rule
+		settings.trace(Event{Kind: RuleTried, Path: st.uri, Rule: r})
+
+		if step.flag == "return" {
+			if r.Code == "" {
+				return nil, nginxStop
+			}
+			return &Match{*r, st.encode(st.expand(r.Target), settings)}, nginxRedirect
+		}
+
+		captures := r.re.FindStringSubmatch(st.uri)
+		if captures == nil {
+			continue
+		}
+		st.captures = captures
+		target := st.expand(r.Target)
+		if r.Code != "" {
+			return &Match{*r, st.encode(target, settings)}, nginxRedirect
+		}
+
+		// An internal rewrite, which changes the path without
+		// sending a redirect.
+		if i := strings.IndexByte(target, '?'); i >= 0 {
+			target = target[:i]
+		}
+		st.uri = target
+		switch step.flag {
+		case "last":
+			return nil, nginxRestart
+		case "break":
+			return nil, nginxBreak
+		}
+		changed = true
+	}
+	if changed {
+		return nil, nginxRestart
+	}
+	return nil, nginxNext
+}
+
+// encode returns the destination the way it would appear in the
+// Location header
+func (st *nginxState) encode(target string, settings Settings) string {
+	if settings.NoEscape {
+		return target
+	}
+	return EncodePath(target)
+}
+
+// findLocation chooses the location block for the path the way nginx
+// does: an exact match, then the longest matching prefix if it uses
+// ^~, then the first matching regular expression, then the longest
+// matching prefix. Returns the groups matched by a regular expression
+// location.
+func (n *nginxServer) findLocation(uri string) (*nginxLocation, []string) {
+	var longest *nginxLocation
+	for i := range n.locations {
+		loc := &n.locations[i]
+		switch loc.modifier {
+		case "=":
+			if uri == loc.path {
+				return loc, nil
+			}
+		case "", "^~":
+			if strings.HasPrefix(uri, loc.path) &&
+				(longest == nil || len(loc.path) > len(longest.path)) {
+				longest = loc
+			}
+		}
+	}
+	if longest != nil && longest.modifier == "^~" {
+		return longest, nil
+	}
+	for i := range n.locations {
+		loc := &n.locations[i]
+		if loc.re == nil {
+			continue
+		}
+		if captures := loc.re.FindStringSubmatch(uri); captures != nil {
+			return loc, captures
+		}
+	}
+	return longest, nil
+}
+
+func (n *nginxServer) firstMatch(target string, settings Settings) *Match {
+	settings.trace(Event{Kind: LookupStarted, Path: target})
+
+	st := nginxState{request: target, uri: DecodePath(target)}
+	m, result := n.run(n.steps, &st, settings)
+	for cycle := 0; result != nginxRedirect && result != nginxStop &&
+		cycle < nginxMaxCycles; cycle++ {
+		loc, captures := n.findLocation(st.uri)
+		if loc == nil {
+			break
+		}
+		if captures != nil {
+			st.captures = captures
+		}
+		m, result = n.run(loc.steps, &st, settings)
+		if result != nginxRestart {
+			break
+		}
+	}
+
+	if result == nginxRedirect {
+		settings.trace(Event{Kind: RuleMatched, Path: target,
+			Rule: &m.Rule, Match: m.Match})
+		return m
+	}
+	settings.trace(Event{Kind: NoMatch, Path: target})
+	return nil
+}
+
+// checkNginxVariables makes sure the destination only uses variables
+// gowhere understands
+func checkNginxVariables(lineNum int, target string) error {
+	for _, name := range nginxVariableRE.FindAllStringSubmatch(target, -1) {
+		n := name[1] + name[2]
+		if _, err := strconv.Atoi(n); err == nil || nginxVariables[n] {
+			continue
+		}
+		return fmt.Errorf("Could not understand variable $%s on line %d", n, lineNum)
+	}
+	return nil
+}
+
+// nginxNamedVariable returns the name of the first variable in the
+// destination other than the numbered captures, such as "uri", or ""
+// if there is none
+func nginxNamedVariable(target string) string {
+	for _, name := range nginxVariableRE.FindAllStringSubmatch(target, -1) {
+		n := name[1] + name[2]
+		if _, err := strconv.Atoi(n); err != nil {
+			return n
+		}
+	}
+	return ""
+}
+
+// nginxRewrite reads a rewrite directive
+func nginxRewrite(d nginxDirective) (nginxStep, error) {
+	var step nginxStep
+	if len(d.Args) < 2 || len(d.Args) > 3 {
+		return step, fmt.Errorf("Could not understand rewrite on line %d: %v",
+			d.LineNum, d.Args)
+	}
+	pattern, replacement := d.Args[0], d.Args[1]
+	if len(d.Args) == 3 {
+		step.flag = d.Args[2]
+	}
+	code, ok := nginxRewriteCodes[step.flag]
+	switch {
+	case ok:
+	case step.flag != "" && step.flag != "last" && step.flag != "break":
+		return step, fmt.Errorf("Could not understand rewrite flag %q on line %d",
+			step.flag, d.LineNum)
+	case strings.HasPrefix(replacement, "http://") || strings.HasPrefix(replacement, "https://"):
+		// A full URL is always a redirect.
+		code = "302"
+	}
+	if err := checkNginxVariables(d.LineNum, replacement); err != nil {
+		return step, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return step, fmt.Errorf("Could not understand regexp '%s' in rewrite on line %d: %v",
+			pattern, d.LineNum, err)
+	}
+	step.rule = Rule{LineNum: d.LineNum, Directive: "redirectmatch",
+		Code: code, Pattern: pattern, Target: replacement, re: re}
+	return step, nil
+}
+
+// nginxReturn reads a return directive. The Rule has the location
+// pattern so it can be compared to paths on its own, and no Code if
+// the response is not a redirect or a 410.
+func nginxReturn(d nginxDirective, loc *nginxLocation) (nginxStep, error) {
+	step := nginxStep{flag: "return"}
+	if len(d.Args) == 0 || len(d.Args) > 2 {
+		return step, fmt.Errorf("Could not understand return on line %d: %v",
+			d.LineNum, d.Args)
+	}
+	code := d.Args[0]
+	target := ""
+	switch {
+	case len(d.Args) == 1 && code != "410" && nginxRedirectCodes[code]:
+		return step, fmt.Errorf("Missing URL in return on line %d: %v",
+			d.LineNum, d.Args)
+	case len(d.Args) == 1 && code != "410" && !nginxRedirectCodes[code]:
+		// return with only a URL is a 302, and nginx only
+		// accepts full URLs there
+		if _, err := strconv.Atoi(code); err == nil {
+			return step, nil
+		}
+		if !strings.HasPrefix(code, "http://") && !strings.HasPrefix(code, "https://") &&
+			!strings.HasPrefix(code, "$scheme") {
+			return step, fmt.Errorf("Could not understand return code %q on line %d",
+				code, d.LineNum)
+		}
+		code, target = "302", d.Args[0]
+	case code == "410":
+	case nginxRedirectCodes[code]:
+		target = d.Args[1]
+	default:
+		// not a redirect
+		return step, nil
+	}
+	if err := checkNginxVariables(d.LineNum, target); err != nil {
+		return step, err
+	}
+
+	params := []string{"redirectmatch", code, "^/"}
+	switch {
+	case loc == nil:
+	case loc.modifier == "=":
+		params = []string{"redirect", code, loc.path}
+	case loc.re != nil:
+		params[2] = loc.re.String()
+	default:
+		params[2] = "^" + regexp.QuoteMeta(loc.path)
+	}
+	if target != "" {
+		params = append(params, target)
+	}
+	r, err := NewRule(d.LineNum, params)
+	if err != nil {
+		return step, err
+	}
+	step.rule = *r
+	return step, nil
+}
+
+// nginxSteps reads the rewrite and return directives from a block,
+// stopping after the first return
+func nginxSteps(directives []nginxDirective, loc *nginxLocation) ([]nginxStep, error) {
+	var steps []nginxStep
+	for _, d := range directives {
+		var step nginxStep
+		var err error
+		switch d.Name {
+		case "rewrite":
+			step, err = nginxRewrite(d)
+		case "return":
+			step, err = nginxReturn(d, loc)
+		case "location":
+			if loc != nil {
+				return nil, fmt.Errorf("Nested location on line %d is not supported",
+					d.LineNum)
+			}
+			continue
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+		if step.flag == "return" {
+			// Nothing after the return is used.
+			break
+		}
+	}
+	return steps, nil
+}
+
+// nginxLocationBlock reads a location block
+func nginxLocationBlock(d nginxDirective) (*nginxLocation, error) {
+	loc := nginxLocation{lineNum: d.LineNum}
+	switch len(d.Args) {
+	case 1:
+		loc.path = d.Args[0]
+		// The modifier may be written without a space.
+		for _, m := range []string{"^~", "~*", "~", "="} {
+			if strings.HasPrefix(loc.path, m) && len(loc.path) > len(m) {
+				loc.modifier, loc.path = m, loc.path[len(m):]
+				break
+			}
+		}
+	case 2:
+		loc.modifier, loc.path = d.Args[0], d.Args[1]
+	default:
+		return nil, fmt.Errorf("Could not understand location on line %d: %v",
+			d.LineNum, d.Args)
+	}
+
+	var err error
+	switch loc.modifier {
+	case "=", "^~", "":
+		if strings.HasPrefix(loc.path, "@") {
+			// A named location is only used by other
+			// directives.
+			return nil, nil
+		}
+	case "~":
+		loc.re, err = regexp.Compile(loc.path)
+	case "~*":
+		loc.re, err = regexp.Compile("(?i)" + loc.path)
+	default:
+		return nil, fmt.Errorf("Could not understand location modifier %q on line %d",
+			loc.modifier, d.LineNum)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not understand regexp '%s' in location on line %d: %v",
+			loc.path, d.LineNum, err)
+	}
+
+	loc.steps, err = nginxSteps(d.Block, &loc)
+	if err != nil {
+		return nil, err
+	}
+	return &loc, nil
+}
+
+// readServer collects the directives of a server block, or of a file
+// to be included in one
+func (n *nginxServer) readServer(directives []nginxDirective) error {
+	steps, err := nginxSteps(directives, nil)
+	if err != nil {
+		return err
+	}
+	n.steps = append(n.steps, steps...)
+	for _, d := range directives {
+		if d.Name != "location" {
+			continue
+		}
+		loc, err := nginxLocationBlock(d)
+		if err != nil {
+			return err
+		}
+		if loc != nil {
+			n.locations = append(n.locations, *loc)
+		}
+	}
+	return nil
+}
+
+// findServer returns the directives of the only server block, or of
+// the whole file if there is no server block
+func findServer(directives []nginxDirective) ([]nginxDirective, error) {
+	var servers []nginxDirective
+	for _, d := range directives {
+		switch d.Name {
+		case "http":
+			inner, err := findServer(d.Block)
+			if err != nil {
+				return nil, err
+			}
+			servers = append(servers, nginxDirective{Name: "server", Block: inner})
+		case "server":
+			servers = append(servers, d)
+		}
+	}
+	switch len(servers) {
+	case 0:
+		return directives, nil
+	case 1:
+		return servers[0].Block, nil
+	}
+	return nil, fmt.Errorf("Found %d server blocks, only one is supported", len(servers))
+}
+
+// rules returns the Rules for the steps that produce redirects, in
+// the order they appear in the file
+func (n *nginxServer) rules() []Rule {
+	var rules []Rule
+	add := func(steps []nginxStep) {
+		for _, s := range steps {
+			if s.rule.Code != "" {
+				rules = append(rules, s.rule)
+			}
+		}
+	}
+	add(n.steps)
+	for _, loc := range n.locations {
+		add(loc.steps)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].LineNum < rules[j].LineNum
+	})
+	return rules
+}
+
+// internalRewrites returns the rewrite directives that change the path
+// without redirecting, in the order they appear in the file. They are
+// not in the Rules of the RuleSet, so they are lost when the rules are
+// converted to another format.
+func (n *nginxServer) internalRewrites() []Rule {
+	var rules []Rule
+	add := func(steps []nginxStep) {
+		for _, s := range steps {
+			if s.flag != "return" && s.rule.Code == "" {
+				rules = append(rules, s.rule)
+			}
+		}
+	}
+	add(n.steps)
+	for _, loc := range n.locations {
+		add(loc.steps)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].LineNum < rules[j].LineNum
+	})
+	return rules
+}
+
+// ParseNginx reads the redirects from an nginx configuration file with
+// one server block, or from a file of directives to be included in a
+// server block, and returns a RuleSet that applies them the way nginx
+// does.
+//
+// The rewrite and return directives outside of any location are
+// applied first, in order. Then the location for the path is chosen:
+// an exact match (location =), the longest matching prefix if it uses
+// ^~, the first matching regular expression (location ~ or ~*), or the
+// longest matching prefix. The rewrite and return directives in the
+// location are applied in order, and a rewrite with the last flag
+// starts the search for a location again, as does a rewrite without a
+// flag after the other directives in the location. Directives inside
+// if blocks are ignored.
+//
+// The Rules are the rewrite directives that produce a redirect and
+// the return directives with a redirect or 410, with the line numbers
+// of those directives.
+func ParseNginx(fd io.Reader) (*RuleSet, error) {
+	directives, err := parseNginxConf(fd)
+	if err != nil {
+		return nil, err
+	}
+	server, err := findServer(directives)
+	if err != nil {
+		return nil, err
+	}
+	var n nginxServer
+	if err := n.readServer(server); err != nil {
+		return nil, err
+	}
+	return &RuleSet{rules: n.rules(), dialect: &n}, nil
+}
@@ -0,0 +1,179 @@
+package gowhere
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNginx(t *testing.T) {
+	data := `server {
+    listen 80;
+    location ~ ^/gone/ {
+        return 410;
+    }
+    location = /a {
+        return 302 /b;
+    }
+    rewrite ^/docs/(.*)$ /new/$1 permanent;
+    location ~* ^/Other$ {
+        return 307 /c;
+    }
+    location = /text {
+        return 200 "hello";
+    }
+}
+`
+	rs, err := ParseNginx(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, r := range rs.rules {
+		actual = append(actual, r.String())
+	}
+	expected := []string{
+		"[line 4] redirectmatch ^/gone/ 410 ",
+		"[line 7] redirect /a 302 /b",
+		"[line 9] redirectmatch ^/docs/(.*)$ 301 /new/$1",
+		"[line 11] redirectmatch (?i)^/Other$ 307 /c",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got %q expected %q", actual, expected)
+	}
+}
+
+func TestNginxPrecedence(t *testing.T) {
+	data := `
+rewrite ^/first/(.*)$ /rewritten/$1 permanent;
+location = /exact {
+    return 301 /from-exact;
+}
+location /docs/ {
+    return 301 /from-prefix;
+}
+location /docs/api/ {
+    return 301 /from-longer-prefix;
+}
+location ^~ /static/ {
+    return 301 /from-static;
+}
+location ~ \.html$ {
+    return 301 /from-regex$uri;
+}
+location ~* ^/DOCS/(.*)\.pdf$ {
+    return 302 /pdf/$1;
+}
+location ~ ^/exact {
+    return 301 /never-used;
+}
+location /first/ {
+    return 301 /never-used;
+}
+location = /gone {
+    return 410;
+}
+location = /text {
+    return 404 "not here";
+}
+location ~ ^/old/(.*)$ {
+    rewrite ^ /new/$1 last;
+}
+location /new/ {
+    return 301 https://example.com$request_uri;
+}
+location ~ ^/loop {
+    rewrite ^ /loop last;
+}
+location /moved/ {
+    rewrite ^/moved/(.*)$ /$1;
+}
+`
+	rs, err := ParseNginx(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		input    string
+		expected string
+		line     int
+	}{
+		{"/first/x", "/rewritten/x", 2},
+		{"/exact", "/from-exact", 4},
+		{"/docs/a", "/from-prefix", 7},
+		// a regular expression wins over a prefix
+		{"/docs/a.html", "/from-regex/docs/a.html", 16},
+		{"/docs/api/b", "/from-longer-prefix", 10},
+		{"/docs/x.pdf", "/pdf/x", 19},
+		// ^~ stops the search for a regular expression
+		{"/static/a.html", "/from-static", 13},
+		{"/gone", "", 28},
+		// the rewrite starts the search again, and the request URI
+		// is still the original path
+		{"/old/page", "https://example.com/old/page", 37},
+		// a rewrite without a flag chooses the location again
+		// after the last step
+		{"/moved/exact", "/from-exact", 4},
+	}
+
+	for n, test := range tests {
+		m := rs.Lookup(test.input, Settings{})
+		if m == nil {
+			t.Errorf("test %d: got no match expected %s", n, test.expected)
+			continue
+		}
+		if m.Match != test.expected || m.LineNum != test.line {
+			t.Errorf("test %d: got %s from line %d expected %s from line %d",
+				n, m.Match, m.LineNum, test.expected, test.line)
+		}
+	}
+
+	for _, input := range []string{"/text", "/other", "/loop"} {
+		if m := rs.Lookup(input, Settings{}); m != nil {
+			t.Errorf("got %v for %s expected no redirect", m, input)
+		}
+	}
+}
+
+func TestNginxProcessChecks(t *testing.T) {
+	data := `server {
+    location = /a { return 301 /b; }
+    location = /b { return 301 /c; }
+    location ~ ^/unused { return 301 /x; }
+}
+`
+	rs, err := ParseNginx(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []Check{{LineNum: 1, Input: "/a", Code: "301", Expected: "/c"}}
+	results := ProcessChecks(rs, checks, Settings{})
+	if len(results.Mismatched) != 0 {
+		t.Errorf("got mismatched %v", results.Mismatched)
+	}
+	if len(results.Unmatched) != 2 || results.Unmatched[1].LineNum != 4 {
+		t.Errorf("got unmatched %v expected lines 3 and 4", results.Unmatched)
+	}
+
+	e := rs.Explain("/a", Settings{})
+	if len(e.Hops) != 3 || e.Hops[0].Winner().Rule.LineNum != 2 {
+		t.Errorf("got explanation %v", e)
+	}
+}
+
+func TestParseNginxUnsupported(t *testing.T) {
+	var tests = []string{
+		"rewrite ^/a$ /b bogus;",
+		"rewrite ^/a$ $scheme://example.com/b permanent;",
+		"location = /a { return 301; }",
+		"location = /a { return /b; }",
+		"location /a { location /a/b { return 301 /b; } }",
+		"server { } server { }",
+	}
+
+	for n, test := range tests {
+		if _, err := ParseNginx(strings.NewReader(test)); err == nil {
+			t.Errorf("test %d: expected an error for %q", n, test)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package gowhere
+
+import (
+	"strings"
+)
+
+const upperhex = "0123456789ABCDEF"
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// isEscape reports whether s[i:] starts with a valid percent-encoded
+// byte and returns the decoded value.
+func isEscape(s string, i int) (byte, bool) {
+	if i+2 >= len(s) || s[i] != '%' {
+		return 0, false
+	}
+	hi, ok1 := unhex(s[i+1])
+	lo, ok2 := unhex(s[i+2])
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return hi<<4 | lo, true
+}
+
+// DecodePath returns the path with percent-encoded characters
+// decoded, the way Apache decodes the URL before comparing it to the
+// patterns in redirect rules.
+//
+// Encoded slashes ("%2F") and percent signs ("%25") are left encoded
+// so they cannot be confused with path separators or escape
+// sequences, and invalid escape sequences are left untouched.
+func DecodePath(path string) string {
+	if !strings.Contains(path, "%") {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c, ok := isEscape(path, i)
+		switch {
+		case !ok:
+			b.WriteByte(path[i])
+		case c == '/' || c == '%':
+			b.WriteString(strings.ToUpper(path[i : i+3]))
+			i += 2
+		default:
+			b.WriteByte(c)
+			i += 2
+		}
+	}
+	return b.String()
+}
+
+// shouldEscape reports whether the byte must be percent-encoded when
+// it appears in the Location header of a redirect.
+func shouldEscape(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return false
+	}
+	switch c {
+	case '-', '_', '.', '~', // unreserved
+		'!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', // sub-delims
+		':', '@', '/', '?', '#':
+		return false
+	}
+	return true
+}
+
+// EncodePath returns the path with the characters that are not
+// allowed in a URL percent-encoded, the way Apache escapes the
+// destination of a redirect before writing the Location
+// header. Existing escape sequences are preserved.
+func EncodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if _, ok := isEscape(path, i); ok {
+			// Normalize the case of the hex digits so that
+			// equivalent paths compare equal.
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(path[i+1 : i+3]))
+			i += 2
+			continue
+		}
+		if shouldEscape(c) {
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// NormalizePath returns the canonical form of the path, with all of
+// the characters that do not need to be encoded decoded and all of
+// the ones that do encoded.
+func NormalizePath(path string) string {
+	return EncodePath(DecodePath(path))
+}
+
+// SamePath reports whether two paths refer to the same location once
+// they are normalized.
+func SamePath(a, b string) bool {
+	return NormalizePath(a) == NormalizePath(b)
+}
@@ -0,0 +1,115 @@
+package gowhere
+
+import (
+	"testing"
+)
+
+func TestDecodePath(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"/plain/path.html", "/plain/path.html"},
+		{"/with%20space", "/with space"},
+		{"/caf%C3%A9", "/café"},
+		{"/lower%c3%a9", "/loweré"},
+		{"/a%2fb", "/a%2Fb"},
+		{"/100%25", "/100%25"},
+		{"/bad%zzescape", "/bad%zzescape"},
+		{"/trailing%2", "/trailing%2"},
+	}
+
+	for n, test := range tests {
+		got := DecodePath(test.input)
+		if got != test.want {
+			t.Errorf("test %d: DecodePath(%q) == %q, expected %q",
+				n, test.input, got, test.want)
+		}
+	}
+}
+
+func TestEncodePath(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"/plain/path.html", "/plain/path.html"},
+		{"/with space", "/with%20space"},
+		{"/café", "/caf%C3%A9"},
+		{"/a%2fb", "/a%2Fb"},
+		{"/query?a=b&c=d", "/query?a=b&c=d"},
+		{"https://example.com/x y", "https://example.com/x%20y"},
+	}
+
+	for n, test := range tests {
+		got := EncodePath(test.input)
+		if got != test.want {
+			t.Errorf("test %d: EncodePath(%q) == %q, expected %q",
+				n, test.input, got, test.want)
+		}
+	}
+}
+
+func TestSamePath(t *testing.T) {
+	if !SamePath("/with space", "/with%20space") {
+		t.Errorf("encoded and decoded spaces should be the same path")
+	}
+	if !SamePath("/caf%c3%a9", "/café") {
+		t.Errorf("encoded and decoded unicode should be the same path")
+	}
+	if SamePath("/a/b", "/a%2Fb") {
+		t.Errorf("encoded slash should not be the same as a separator")
+	}
+}
+
+func TestRuleMatchDecodesInput(t *testing.T) {
+	r, _ := NewRule(1, []string{"redirectmatch", "301",
+		"^/old page/(.*)$",
+		"/new page/$1"})
+	s := r.Match("/old%20page/caf%C3%A9")
+	if s != "/new%20page/caf%C3%A9" {
+		t.Errorf("received %s instead of /new%%20page/caf%%C3%%A9", s)
+	}
+	s = r.Explain("/old%20page/caf%C3%A9", Settings{NoEscape: true}).Match
+	if s != "/new page/café" {
+		t.Errorf("received %s instead of unescaped value", s)
+	}
+}
+
+func TestRuleMatchEncodedSlash(t *testing.T) {
+	r, _ := NewRule(1, []string{"redirect", "301",
+		"/a/b",
+		"/c"})
+	s := r.Match("/a%2Fb")
+	if s != "" {
+		t.Errorf("received %s instead of empty string", s)
+	}
+}
+
+func TestCheckExpects(t *testing.T) {
+	c, err := NewCheck(1, []string{"/old", "301", "/new page"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !c.Expects("/new%20page") {
+		t.Errorf("normalized comparison should match encoded location")
+	}
+
+	c, err = NewCheck(1, []string{"/old", "301", "/new page", "exact=true"})
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if !c.Exact {
+		t.Errorf("exact option was not set")
+	}
+	if c.Expects("/new%20page") {
+		t.Errorf("exact comparison should not match encoded location")
+	}
+}
+
+func TestNewCheckBadOption(t *testing.T) {
+	_, err := NewCheck(1, []string{"/old", "301", "/new", "color=blue"})
+	if err == nil {
+		t.Errorf("should have an error for unknown option")
+	}
+}
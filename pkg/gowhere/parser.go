@@ -2,7 +2,12 @@ package gowhere
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -10,7 +15,7 @@ import (
 // and returns a RuleSet containing all of them. Stops on the first
 // error parsing the file.
 func ParseRules(fd io.Reader) (*RuleSet, error) {
-	var rules RuleSet
+	var rules []Rule
 	lineNum := 0
 	input := bufio.NewScanner(fd)
 	for input.Scan() {
@@ -26,11 +31,92 @@ func ParseRules(fd io.Reader) (*RuleSet, error) {
 
 		r, err := NewRule(lineNum, strings.Fields(line))
 		if err != nil {
-			return &rules, err
+			return NewRuleSet(rules), err
 		}
-		rules.rules = append(rules.rules, *r)
+		rules = append(rules, *r)
 	}
-	return &rules, nil
+	return NewRuleSet(rules), nil
+}
+
+// ruleFormats holds the function that reads each format of rules
+// file, by name
+var ruleFormats = map[string]func(io.Reader) (*RuleSet, error){
+	"csv":        ParseCSV,
+	"htaccess":   ParseRules,
+	"cloudflare": ParseCloudflare,
+	"netlify":    ParseNetlify,
+	"nginx":      ParseNginx,
+	"tsv":        ParseTSV,
+}
+
+// RuleFormats returns the names of the formats ParseRulesFile can
+// read, in order.
+func RuleFormats() []string {
+	var names []string
+	for name := range ruleFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isNginxConf tests whether the data looks like an nginx configuration
+// file, with directives ending in ";" or blocks in braces, rather than
+// one redirect directive per line.
+func isNginxConf(data []byte) bool {
+	input := bufio.NewScanner(bytes.NewReader(data))
+	for input.Scan() {
+		line := strings.TrimSpace(input.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if strings.HasSuffix(line, ";") || strings.HasSuffix(line, "{") ||
+			line == "}" ||
+			(strings.HasSuffix(line, "}") && strings.Contains(line, ";")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleFileFormat chooses the format of a rules file from its name and
+// contents
+func ruleFileFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".tsv":
+		return "tsv"
+	}
+	if filepath.Base(filename) == "_redirects" {
+		return "netlify"
+	}
+	if isNginxConf(data) {
+		return "nginx"
+	}
+	return "htaccess"
+}
+
+// ParseRulesFile reads the redirect rules from the named file in the
+// format with the given name (one of RuleFormats) and returns a
+// RuleSet containing all of them. If the format is "", it is chosen
+// from the file: ".csv" and ".tsv" files are parsed with ParseCSV and
+// ParseTSV, files named "_redirects" with ParseNetlify, files with
+// directives ending in ";" or blocks in braces with ParseNginx, and
+// all other files with ParseRules.
+func ParseRulesFile(filename string, format string) (*RuleSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" {
+		format = ruleFileFormat(filename, data)
+	}
+	parse, ok := ruleFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("Unknown rules file format %q", format)
+	}
+	return parse(bytes.NewReader(data))
 }
 
 // ParseChecks reads the rule checks and returns a slice of Check
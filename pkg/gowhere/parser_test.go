@@ -2,6 +2,8 @@ package gowhere
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -50,3 +52,69 @@ func TestParseRulesIgnoreBlankLines(t *testing.T) {
 		t.Errorf("got %d rules expected 0", len(rs.rules))
 	}
 }
+
+func TestRuleFileFormat(t *testing.T) {
+	var tests = []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{".htaccess", "redirect 301 /a /b\n", "htaccess"},
+		{"redirects.conf", "# Apache rules\nredirect 301 /a /b\n", "htaccess"},
+		{"redirects.conf", "location = /a { return 301 /b; }\n", "nginx"},
+		{"site.nginx", "# nginx\nrewrite ^/a$ /b permanent;\n", "nginx"},
+		{"server.conf", "server {\n    listen 80;\n}\n", "nginx"},
+		{"redirects.conf", "RewriteRule ^/a$ %{REQUEST_URI}\n", "htaccess"},
+		{"_redirects", "/a /b\n", "netlify"},
+		{"redirects.csv", "source,target\n/a,/b\n", "csv"},
+		{"redirects.TSV", "/a\t/b\n", "tsv"},
+	}
+
+	for n, test := range tests {
+		actual := ruleFileFormat(test.name, []byte(test.data))
+		if actual != test.expected {
+			t.Errorf("test %d: got %s expected %s", n, actual, test.expected)
+		}
+	}
+}
+
+func TestParseRulesFile(t *testing.T) {
+	dir := t.TempDir()
+	var tests = []struct {
+		name    string
+		format  string
+		data    string
+		dialect bool
+	}{
+		{"a.htaccess", "", "redirect 301 /a /b\n", false},
+		{"a.conf", "", "redirect 301 /a /b\n", false},
+		{"b.conf", "", "location = /a { return 301 /b; }\n", true},
+		{"rules", "nginx", "location = /a { return 301 /b; }\n", true},
+		{"rules.txt", "netlify", "/a /b\n", false},
+		{"rules.txt", "cloudflare", "/a /b\n", false},
+		{"_redirects", "", "/a /b\n", false},
+	}
+
+	for n, test := range tests {
+		filename := filepath.Join(dir, test.name)
+		if err := os.WriteFile(filename, []byte(test.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		rs, err := ParseRulesFile(filename, test.format)
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		if (rs.dialect != nil) != test.dialect {
+			t.Errorf("test %d: got dialect %v", n, rs.dialect)
+		}
+		if m := rs.Lookup("/a", Settings{}); m == nil || m.Match != "/b" {
+			t.Errorf("test %d: got %v expected a redirect to /b", n, m)
+		}
+	}
+
+	filename := filepath.Join(dir, "a.htaccess")
+	if _, err := ParseRulesFile(filename, "apache"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
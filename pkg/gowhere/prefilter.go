@@ -0,0 +1,178 @@
+package gowhere
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// prefilter finds the regexp rules that could match a path by
+// looking for the literal text required by their patterns, using an
+// Aho-Corasick automaton to search for all of the literals at once.
+type prefilter struct {
+	nodes []acNode
+	// rule positions, by literal id
+	rules [][]int
+}
+
+// acNode is one state of the Aho-Corasick automaton
+type acNode struct {
+	next map[byte]int
+	// the state for the longest proper suffix of this state
+	fail int
+	// the nearest state along the fail links with output
+	dict int
+	// the id of the literal ending at this state, or -1
+	literal int
+}
+
+// newPrefilter creates an empty prefilter
+func newPrefilter() *prefilter {
+	return &prefilter{
+		nodes: []acNode{{literal: -1, dict: -1}},
+	}
+}
+
+// add registers the rule position to be returned for paths
+// containing the literal. The automaton must be rebuilt with build
+// before it is used.
+func (p *prefilter) add(literal string, rule int) {
+	state := 0
+	for i := 0; i < len(literal); i++ {
+		c := literal[i]
+		next, ok := p.nodes[state].next[c]
+		if !ok {
+			next = len(p.nodes)
+			p.nodes = append(p.nodes, acNode{literal: -1, dict: -1})
+			if p.nodes[state].next == nil {
+				p.nodes[state].next = make(map[byte]int)
+			}
+			p.nodes[state].next[c] = next
+		}
+		state = next
+	}
+	if p.nodes[state].literal < 0 {
+		p.nodes[state].literal = len(p.rules)
+		p.rules = append(p.rules, nil)
+	}
+	id := p.nodes[state].literal
+	p.rules[id] = append(p.rules[id], rule)
+}
+
+// build computes the fail and dictionary links for the automaton
+func (p *prefilter) build() {
+	queue := []int{}
+	for _, child := range p.nodes[0].next {
+		p.nodes[child].fail = 0
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for c, child := range p.nodes[state].next {
+			fail := p.nodes[state].fail
+			for {
+				if next, ok := p.nodes[fail].next[c]; ok && next != child {
+					fail = next
+					break
+				}
+				if fail == 0 {
+					break
+				}
+				fail = p.nodes[fail].fail
+			}
+			p.nodes[child].fail = fail
+			if p.nodes[fail].literal >= 0 {
+				p.nodes[child].dict = fail
+			} else {
+				p.nodes[child].dict = p.nodes[fail].dict
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// collect appends the positions of the rules with a literal found in
+// path to candidates
+func (p *prefilter) collect(path string, candidates []int) []int {
+	if len(p.rules) == 0 {
+		return candidates
+	}
+
+	found := make(map[int]bool)
+	state := 0
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		for {
+			if next, ok := p.nodes[state].next[c]; ok {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = p.nodes[state].fail
+		}
+		for out := state; out > 0; out = p.nodes[out].dict {
+			if id := p.nodes[out].literal; id >= 0 {
+				if found[id] {
+					// The rest of the chain has been
+					// reported already, too.
+					break
+				}
+				found[id] = true
+				candidates = append(candidates, p.rules[id]...)
+			}
+		}
+	}
+	return candidates
+}
+
+// requiredLiteral returns the longest literal text that must appear
+// in any path matching the regexp, or "" if there is none.
+func requiredLiteral(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return ""
+	}
+	return longestRequired(re.Simplify())
+}
+
+// longestRequired implements requiredLiteral for a parsed regexp
+func longestRequired(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return ""
+		}
+		return string(re.Rune)
+	case syntax.OpCapture, syntax.OpPlus:
+		return longestRequired(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return longestRequired(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		// Adjacent literal parts of the concatenation form a
+		// single longer literal.
+		longest := ""
+		var run strings.Builder
+		for _, sub := range re.Sub {
+			if literalPrefix(sub, &run) {
+				continue
+			}
+			if run.Len() > len(longest) {
+				longest = run.String()
+			}
+			run.Reset()
+			if inner := longestRequired(sub); len(inner) > len(longest) {
+				longest = inner
+			}
+		}
+		if run.Len() > len(longest) {
+			longest = run.String()
+		}
+		return longest
+	}
+	return ""
+}
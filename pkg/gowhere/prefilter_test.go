@@ -0,0 +1,69 @@
+package gowhere
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestRequiredLiteral(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		want    string
+	}{
+		{"/renamed/old/", "/renamed/old/"},
+		{"/legacy/(.*)$", "/legacy/"},
+		{`(.*)/old_page\.html$`, "/old_page.html"},
+		{"/(a|b)/index.html", "/index"},
+		{`/(a|b)/index\.html`, "/index.html"},
+		{"(foo)+barbaz", "barbaz"},
+		{"(longer-literal)+x", "longer-literal"},
+		{"(?i)/docs/", ""},
+		{".*", ""},
+		{"a|b", ""},
+	}
+
+	for n, test := range tests {
+		got := requiredLiteral(test.pattern)
+		if got != test.want {
+			t.Errorf("test %d: requiredLiteral(%q) == %q, expected %q",
+				n, test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestPrefilterCollect(t *testing.T) {
+	p := newPrefilter()
+	p.add("he", 0)
+	p.add("she", 1)
+	p.add("his", 2)
+	p.add("hers", 3)
+	p.add("she", 4)
+	p.build()
+
+	var tests = []struct {
+		path string
+		want []int
+	}{
+		{"ushers", []int{0, 1, 3, 4}},
+		{"this", []int{2}},
+		{"nothing", nil},
+		{"shehe", []int{0, 1, 4}},
+	}
+
+	for n, test := range tests {
+		got := p.collect(test.path, nil)
+		sort.Ints(got)
+		if len(got) != len(test.want) {
+			t.Errorf("test %d: collect(%q) == %v, expected %v",
+				n, test.path, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("test %d: collect(%q) == %v, expected %v",
+					n, test.path, got, test.want)
+				break
+			}
+		}
+	}
+}
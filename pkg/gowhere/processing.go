@@ -1,7 +1,8 @@
 package gowhere
 
 import (
-	"fmt"
+	"regexp"
+	"sync"
 )
 
 // Mismatched holds the results when a Check produces unexpected
@@ -11,6 +12,16 @@ type Mismatched struct {
 	Matches []Match
 }
 
+// Diverged holds the results when the redirect chain for a Check
+// does not follow the expected chain.
+type Diverged struct {
+	Check   Check
+	Matches []Match
+	// The index of the first hop that does not follow the expected
+	// chain
+	Hop int
+}
+
 // Results holds the output of processing all of the Checks and Rules.
 type Results struct {
 	// inputs that did not match the expected value
@@ -19,83 +30,206 @@ type Results struct {
 	ExceededHops []Mismatched
 	// inputs that result in redirect cycles
 	Cycles []Mismatched
+	// inputs that did not follow the expected redirect chain
+	Diverged []Diverged
 	// rules that never matched
 	Unmatched []Rule
 	// rules that were matched properly
 	Matched []Rule
+	// checks that were not selected by the filters in the Settings
+	Skipped []Check
 }
 
 // Settings holds the parameters for controlling the processing.
 type Settings struct {
 	Verbose bool
 	MaxHops int
+	// Do not encode the destination of redirects (like the NE
+	// flag for mod_rewrite)
+	NoEscape bool
+	// Only run the checks with at least one of these tags
+	Tags []string
+	// Only run the checks with inputs matching this regexp
+	Run *regexp.Regexp
+	// The number of checks to evaluate in parallel
+	Jobs int
+	// Receives the Events describing the progress of the
+	// processing. When Verbose is set without a Tracer, the Events
+	// are logged to stderr.
+	Tracer Tracer
+}
+
+// tracer returns the Tracer for the settings, or nil if there is none
+func (s *Settings) tracer() Tracer {
+	if s.Tracer != nil {
+		return s.Tracer
+	}
+	if s.Verbose {
+		return defaultTracer
+	}
+	return nil
+}
+
+// trace sends the Event to the Tracer, if there is one
+func (s *Settings) trace(event Event) {
+	if tracer := s.tracer(); tracer != nil {
+		tracer.Trace(event)
+	}
+}
+
+// filtered tests whether the settings select a subset of the checks
+func (s *Settings) filtered() bool {
+	return len(s.Tags) > 0 || s.Run != nil
+}
+
+// findMatches runs the rules against one check, reporting on the
+// progress to the Tracer.
+func findMatches(rules *RuleSet, check *Check, settings Settings) []Match {
+	settings.trace(Event{Kind: CheckStarted, Check: check})
+	matches := rules.FindMatches(check, settings)
+	settings.trace(Event{Kind: CheckFinished, Check: check,
+		Hops: len(matches), Matches: matches})
+	return matches
+}
+
+// findAllMatches runs the rules against all of the checks, using
+// settings.Jobs workers. The matches are returned in the same order
+// as the checks, and the Events for each check are buffered and sent
+// to the Tracer in the same order so they are not interleaved.
+func findAllMatches(rules *RuleSet, checks []Check, settings Settings) [][]Match {
+	found := make([][]Match, len(checks))
+
+	if settings.Jobs <= 1 {
+		for i := range checks {
+			found[i] = findMatches(rules, &checks[i], settings)
+		}
+		return found
+	}
+
+	traces := make([]eventBuffer, len(checks))
+	tracer := settings.tracer()
+	todo := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < settings.Jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range todo {
+				s := settings
+				if tracer != nil {
+					s.Tracer = &traces[i]
+				}
+				found[i] = findMatches(rules, &checks[i], s)
+			}
+		}()
+	}
+	for i := range checks {
+		todo <- i
+	}
+	close(todo)
+	wg.Wait()
+
+	if tracer != nil {
+		for i := range traces {
+			traces[i].replay(tracer)
+		}
+	}
+	return found
+}
+
+// addResult compares the matches for a check to what the check
+// expects and records any problem.
+func (r *Results) addResult(check Check, matches []Match, settings Settings) {
+	if len(matches) == 0 {
+		if check.Code == "200" {
+			// The check is ensuring that a URL
+			// does *not* redirect, so the check is
+			// passing.
+		} else {
+			// The check did not match any rules,
+			// so record the mismatch as having
+			// not redirected.
+			r.Mismatched = append(
+				r.Mismatched,
+				Mismatched{check, matches})
+		}
+		return
+	}
+
+	// Look for cycles, mismatches, etc.
+	finalMatch := matches[len(matches)-1]
+	if SamePath(check.Input, finalMatch.Match) {
+		// The matches resulted in going back to
+		// the starting point, so we have a cycle
+		r.Cycles = append(r.Cycles,
+			Mismatched{check, matches})
+	} else if maxHops := check.maxHops(settings); maxHops > 0 && len(matches) > maxHops {
+		// Regardless of whether we ended up
+		// in the right place, it took too
+		// many hops to get there.
+		r.ExceededHops = append(
+			r.ExceededHops,
+			Mismatched{check, matches})
+	} else if hop := check.Diverges(matches); hop >= 0 {
+		// The redirect chain did not go
+		// through the expected locations.
+		r.Diverged = append(r.Diverged,
+			Diverged{check, matches, hop})
+	} else if check.Code != finalMatch.Code ||
+		!check.Expects(finalMatch.Match) {
+		// There is at least one match, but
+		// the final URL and code are not the
+		// ones we expected.
+		r.Mismatched = append(
+			r.Mismatched,
+			Mismatched{check, matches})
+	}
 }
 
 // ProcessChecks runs all of the rules against the checks and produce
-// a results set.
+// a results set. The RuleSet is not modified, so the checks may be
+// evaluated in parallel by setting settings.Jobs. The results are
+// always reported in the same order as the checks.
 func ProcessChecks(rules *RuleSet, checks []Check, settings Settings) *Results {
 	r := Results{}
 	used := make(map[int]bool)
+	// When only some of the checks are run, only report on the
+	// rules reachable from those checks.
+	reached := make(map[int]bool)
 
+	var selected []Check
 	for _, check := range checks {
-		if settings.Verbose {
-			fmt.Printf("\ncheck: %v\n", check)
+		if !check.Selected(settings) {
+			r.Skipped = append(r.Skipped, check)
+			continue
 		}
-		matches := rules.FindMatches(&check, settings)
-		if settings.Verbose {
-			fmt.Printf("found %d matches: %v\n", len(matches), matches)
+		selected = append(selected, check)
+	}
+
+	found := findAllMatches(rules, selected, settings)
+
+	for i, check := range selected {
+		matches := found[i]
+		for _, m := range matches {
+			reached[m.LineNum] = true
 		}
-		if len(matches) == 0 {
-			if check.Code == "200" {
-				// The check is ensuring that a URL
-				// does *not* redirect, so the check is
-				// passing.
-			} else {
-				// The check did not match any rules,
-				// so record the mismatch as having
-				// not redirected.
-				r.Mismatched = append(
-					r.Mismatched,
-					Mismatched{check, matches})
-			}
-		} else {
+		if len(matches) > 0 {
 			// Record only the first match as used,
 			// encouraging individual checks for each rule.
 			used[matches[0].LineNum] = true
-
-			// Look for cycles, mismatches, etc.
-			finalMatch := matches[len(matches)-1]
-			if check.Input == finalMatch.Match {
-				// The matches resulted in going back to
-				// the starting point, so we have a cycle
-				r.Cycles = append(r.Cycles,
-					Mismatched{check, matches})
-			} else if settings.MaxHops > 0 && len(matches) > settings.MaxHops {
-				// Regardless of whether we ended up
-				// in the right place, it took too
-				// many hops to get there.
-				r.ExceededHops = append(
-					r.ExceededHops,
-					Mismatched{check, matches})
-			} else if check.Code != finalMatch.Code ||
-				check.Expected != finalMatch.Match {
-				// There is at least one match, but
-				// the final URL and code are not the
-				// ones we expected.
-				r.Mismatched = append(
-					r.Mismatched,
-					Mismatched{check, matches})
-			} else {
-				// Recognize that the first
-				// rule was checked properly.
-				used[matches[0].LineNum] = true
-			}
 		}
+		r.addResult(check, matches, settings)
 	}
 
 	for _, rule := range rules.rules {
 		if used[rule.LineNum] {
 			r.Matched = append(r.Matched, rule)
+		} else if settings.filtered() && !reached[rule.LineNum] &&
+			!rule.selected(settings) {
+			// The rule is not related to the checks
+			// that were run.
+			continue
 		} else {
 			r.Unmatched = append(r.Unmatched, rule)
 		}
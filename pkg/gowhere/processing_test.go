@@ -0,0 +1,118 @@
+package gowhere
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestProcessChecksDiverged(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /b /c
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	c, _ := NewCheck(1, []string{"/a", "301", "/c", "chain=/x,/c"})
+	results := ProcessChecks(rs, []Check{*c}, Settings{})
+	if len(results.Diverged) != 1 {
+		t.Fatalf("got %d diverged expected 1: %v",
+			len(results.Diverged), results)
+	}
+	if results.Diverged[0].Hop != 0 {
+		t.Errorf("diverged at hop %d expected 0", results.Diverged[0].Hop)
+	}
+}
+
+func TestProcessChecksCheckMaxHops(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /b /c
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	c, _ := NewCheck(1, []string{"/a", "301", "/c", "max-hops=1"})
+	results := ProcessChecks(rs, []Check{*c}, Settings{MaxHops: 5})
+	if len(results.ExceededHops) != 1 {
+		t.Errorf("got %d exceeded hops expected 1: %v",
+			len(results.ExceededHops), results)
+	}
+}
+
+func TestProcessChecksFilterTags(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /c /d
+redirect 301 /e /f
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	c1, _ := NewCheck(1, []string{"/a", "301", "/b", "tags=api"})
+	c2, _ := NewCheck(2, []string{"/c", "301", "/d", "tags=docs"})
+	results := ProcessChecks(rs, []Check{*c1, *c2},
+		Settings{Tags: []string{"api"}})
+	if len(results.Skipped) != 1 || results.Skipped[0].LineNum != 2 {
+		t.Errorf("got skipped %v expected check on line 2",
+			results.Skipped)
+	}
+	if len(results.Matched) != 1 || results.Matched[0].LineNum != 1 {
+		t.Errorf("got matched %v expected rule on line 1",
+			results.Matched)
+	}
+	// The other rules are not reachable from the selected check.
+	if len(results.Unmatched) != 0 {
+		t.Errorf("got unmatched %v expected none", results.Unmatched)
+	}
+}
+
+func TestProcessChecksFilterRun(t *testing.T) {
+	data := []byte(`redirect 301 /project/a /project/b
+redirect 301 /project/c /project/d
+redirect 301 /other/e /other/f
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	c1, _ := NewCheck(1, []string{"/project/a", "301", "/project/b"})
+	c2, _ := NewCheck(2, []string{"/other/e", "301", "/other/f"})
+	results := ProcessChecks(rs, []Check{*c1, *c2},
+		Settings{Run: regexp.MustCompile("/project/.*")})
+	if len(results.Skipped) != 1 {
+		t.Errorf("got %d skipped expected 1", len(results.Skipped))
+	}
+	// The untested rule has a pattern matching the filter.
+	if len(results.Unmatched) != 1 || results.Unmatched[0].LineNum != 2 {
+		t.Errorf("got unmatched %v expected rule on line 2",
+			results.Unmatched)
+	}
+}
+
+func TestProcessChecksParallel(t *testing.T) {
+	var rules, checks bytes.Buffer
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&rules, "redirect 301 /old/%d /new/%d\n", i, i)
+		// Every third check fails.
+		if i%3 == 0 {
+			fmt.Fprintf(&checks, "/old/%d 301 /wrong/%d\n", i, i)
+		} else {
+			fmt.Fprintf(&checks, "/old/%d 301 /new/%d\n", i, i)
+		}
+	}
+	rs, _ := ParseRules(&rules)
+	cs, _ := ParseChecks(&checks)
+
+	var serialEvents, parallelEvents eventBuffer
+	serial := ProcessChecks(rs, cs,
+		Settings{Tracer: &serialEvents})
+	parallel := ProcessChecks(rs, cs,
+		Settings{Tracer: &parallelEvents, Jobs: 8})
+
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("parallel results differ from serial results")
+	}
+	if !reflect.DeepEqual(serialEvents, parallelEvents) {
+		t.Errorf("parallel events differ from serial events")
+	}
+	if len(parallel.Mismatched) != 17 {
+		t.Errorf("got %d mismatched expected 17", len(parallel.Mismatched))
+	}
+	for i := 1; i < len(parallel.Mismatched); i++ {
+		if parallel.Mismatched[i-1].Check.LineNum > parallel.Mismatched[i].Check.LineNum {
+			t.Errorf("mismatched results are not in input order")
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package gowhere
+
+import (
+	"sort"
+)
+
+// ReplayOutcome holds the redirect chain for one path in the traffic
+type ReplayOutcome struct {
+	TrafficPath
+	Matches []Match
+}
+
+// RuleHits holds the number of requests handled by a Rule
+type RuleHits struct {
+	Rule Rule
+	Hits int
+}
+
+// ReplayReport summarizes what the rules do with the traffic
+type ReplayReport struct {
+	// The number of unique paths
+	Paths int
+	// The total number of requests
+	Hits int
+	// The number of requests by final HTTP response code ("200"
+	// for paths that are not redirected)
+	Status map[string]int
+	// paths that result in redirect cycles
+	Cycles []ReplayOutcome
+	// paths with more hops than allowed, or more than 1 hop if
+	// Settings.MaxHops is not set
+	LongChains []ReplayOutcome
+	// paths that end at a rule without a destination, such as a
+	// 410
+	Nowhere []ReplayOutcome
+	// rules matched by the traffic at any hop, with the most
+	// requests first
+	TopRules []RuleHits
+}
+
+// Replay runs each path in the traffic through the rules and reports
+// on the results.
+func Replay(rules *RuleSet, traffic []TrafficPath, settings Settings) *ReplayReport {
+	r := ReplayReport{Status: make(map[string]int)}
+	// hits by rule line number
+	ruleHits := make(map[int]*RuleHits)
+	maxHops := settings.MaxHops
+	if maxHops <= 0 {
+		maxHops = 1
+	}
+
+	for _, t := range traffic {
+		r.Paths++
+		r.Hits += t.Hits
+
+		check := Check{Input: t.Path}
+		matches, end := rules.followChain(&check, settings)
+		outcome := ReplayOutcome{t, matches}
+
+		if len(matches) == 0 {
+			r.Status["200"] += t.Hits
+			continue
+		}
+
+		r.Status[matches[len(matches)-1].Code] += t.Hits
+		// Count each rule once per request, even if it
+		// appears more than once in the chain.
+		seen := make(map[int]bool)
+		for _, m := range matches {
+			if seen[m.LineNum] {
+				continue
+			}
+			seen[m.LineNum] = true
+			h, ok := ruleHits[m.LineNum]
+			if !ok {
+				h = &RuleHits{Rule: m.Rule}
+				ruleHits[m.LineNum] = h
+			}
+			h.Hits += t.Hits
+		}
+
+		switch {
+		case end == CycleDetected:
+			r.Cycles = append(r.Cycles, outcome)
+		case len(matches) > maxHops:
+			r.LongChains = append(r.LongChains, outcome)
+		case end == NoTarget:
+			r.Nowhere = append(r.Nowhere, outcome)
+		}
+	}
+
+	for _, h := range ruleHits {
+		r.TopRules = append(r.TopRules, *h)
+	}
+	sort.Slice(r.TopRules, func(i, j int) bool {
+		if r.TopRules[i].Hits != r.TopRules[j].Hits {
+			return r.TopRules[i].Hits > r.TopRules[j].Hits
+		}
+		return r.TopRules[i].Rule.LineNum < r.TopRules[j].Rule.LineNum
+	})
+
+	return &r
+}
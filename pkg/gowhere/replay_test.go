@@ -0,0 +1,49 @@
+package gowhere
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /b /c
+redirect 410 /gone
+redirect 301 /cycle/a /cycle/b
+redirect 301 /cycle/b /cycle/a
+redirect 301 /d /b
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	traffic := []TrafficPath{
+		{"/a", 5},
+		{"/d", 2},
+		{"/gone", 1},
+		{"/cycle/a", 1},
+		{"/not/redirected", 10},
+	}
+	report := Replay(rs, traffic, Settings{})
+
+	if report.Paths != 5 || report.Hits != 19 {
+		t.Errorf("got %d paths and %d hits expected 5 and 19",
+			report.Paths, report.Hits)
+	}
+	if report.Status["200"] != 10 || report.Status["301"] != 8 ||
+		report.Status["410"] != 1 {
+		t.Errorf("got status %v", report.Status)
+	}
+	if len(report.Cycles) != 1 || report.Cycles[0].Path != "/cycle/a" {
+		t.Errorf("got cycles %v", report.Cycles)
+	}
+	if len(report.LongChains) != 2 || report.LongChains[1].Path != "/d" {
+		t.Errorf("got long chains %v", report.LongChains)
+	}
+	if len(report.Nowhere) != 1 || report.Nowhere[0].Path != "/gone" {
+		t.Errorf("got nowhere %v", report.Nowhere)
+	}
+	// /b is hit by both /a and /d.
+	top := report.TopRules[0]
+	if top.Rule.LineNum != 2 || top.Hits != 7 {
+		t.Errorf("got top rule %v with %d hits expected line 2 with 7",
+			top.Rule, top.Hits)
+	}
+}
@@ -3,13 +3,15 @@ package gowhere
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // Rule represents one redirect rule
 type Rule struct {
 	// The line of the input file where the rule was found
 	LineNum int
-	// The Apache directive ("redirect" or "redirectmatch")
+	// The Apache directive ("redirect" or "redirectmatch"), or
+	// "netlify" for a rule from a _redirects file
 	Directive string
 	// The HTTP response code ("301", etc.)
 	Code string
@@ -36,7 +38,17 @@ type Match struct {
 	Match string
 }
 
-// NewRule creates a Rule from the strings on the input line
+// statusNames holds the names Apache allows in place of the status
+// code in a rule, and the codes they stand for
+var statusNames = map[string]string{
+	"permanent": "301",
+	"temp":      "302",
+	"seeother":  "303",
+	"gone":      "410",
+}
+
+// NewRule creates a Rule from the strings on the input line. A status
+// name such as "permanent" is replaced by its code.
 func NewRule(lineNum int, params []string) (*Rule, error) {
 	if len(params) < 3 {
 		return nil, fmt.Errorf("Not enough parameters on line %d: %v",
@@ -49,15 +61,19 @@ func NewRule(lineNum int, params []string) (*Rule, error) {
 
 	r := Rule{LineNum: lineNum, Directive: params[0]}
 
+	code := params[1]
+	if c, ok := statusNames[strings.ToLower(code)]; ok {
+		code = c
+	}
 	if len(params) == 4 {
 		// redirect code pattern target
-		r.Code = params[1]
+		r.Code = code
 		r.Pattern = params[2]
 		r.Target = params[3]
-	} else if params[1] == "410" {
+	} else if code == "410" {
 		// The page has been deleted and is not coming
 		// back (nil target).
-		r.Code = params[1]
+		r.Code = code
 		r.Pattern = params[2]
 	} else {
 		// redirect pattern target
@@ -90,25 +106,122 @@ func NewRule(lineNum int, params []string) (*Rule, error) {
 //
 // Returns the matching string, so when the rule pattern is a regexp
 // and the target includes substitutions the return value is the
-// actual path to which the redirect would send the browser.
+// actual path to which the redirect would send the browser. Rules
+// without a destination (such as a 410) also return an empty string,
+// so use Explain to tell them apart from a rule that does not match.
+//
+// Like Apache, the target is decoded before it is compared to the
+// pattern and the result is encoded the way it would appear in the
+// Location header of the response.
 func (r *Rule) Match(target string) string {
+	return r.Explain(target, Settings{}).Match
+}
+
+// Outcome describes the result of comparing a Rule to a path
+type Outcome int
+
+const (
+	// Matched means the rule matches the path
+	Matched Outcome = iota
+	// LiteralMismatch means the path is not the same as the
+	// literal pattern of a "redirect" rule
+	LiteralMismatch
+	// RegexpMismatch means the regexp pattern of a
+	// "redirectmatch" rule does not match the path
+	RegexpMismatch
+)
+
+var outcomeNames = map[Outcome]string{
+	Matched:         "matched",
+	LiteralMismatch: "literal pattern differs",
+	RegexpMismatch:  "regexp does not match",
+}
+
+// Return a description of the Outcome
+func (o Outcome) String() string {
+	if name, ok := outcomeNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Attempt holds the details of comparing a Rule to a path
+type Attempt struct {
+	Rule *Rule
+	// The path given to the rule
+	Path string
+	// Whether the rule matched, or why not
+	Outcome Outcome
+	// The text matched by the pattern of a "redirectmatch" rule
+	// ($0) and each of its groups ($1, $2, etc.), for the first
+	// match
+	Captures []string
+	// The destination of the redirect, with the group references
+	// in Target expanded
+	Match string
+}
+
+// Explain compares the rule to the target and describes the result.
+func (r *Rule) Explain(target string, settings Settings) Attempt {
+	a := Attempt{Rule: r, Path: target}
+	target = DecodePath(target)
+	result := ""
+
 	switch r.Directive {
 
 	case "redirect":
-		if r.Pattern == target {
-			return r.Target
+		if DecodePath(r.Pattern) != target {
+			a.Outcome = LiteralMismatch
+			return a
+		}
+		result = r.Target
+
+	case "netlify":
+		submatches := r.re.FindStringSubmatchIndex(target)
+		if submatches == nil {
+			a.Outcome = RegexpMismatch
+			return a
 		}
+		template := netlifyTemplate(r.re, r.Target)
+		result = string(r.re.ExpandString(nil, template, target, submatches))
+		a.Captures = r.re.FindStringSubmatch(target)
 
 	case "redirectmatch":
 		// if the pattern matches, expand the references in the target
 		// to what was matched in the input so we can return a real
 		// path rather than a regexp
-		result := []byte{}
-		for _, submatches := range r.re.FindAllStringSubmatchIndex(target, -1) {
-			result = r.re.ExpandString(result, r.Target, target, submatches)
+		all := r.re.FindAllStringSubmatchIndex(target, -1)
+		if all == nil {
+			a.Outcome = RegexpMismatch
+			return a
 		}
-		return string(result)
+		expanded := []byte{}
+		for _, submatches := range all {
+			expanded = r.re.ExpandString(expanded, r.Target, target, submatches)
+		}
+		result = string(expanded)
+		for i := 0; i < len(all[0]); i += 2 {
+			if all[0][i] < 0 {
+				a.Captures = append(a.Captures, "")
+			} else {
+				a.Captures = append(a.Captures, target[all[0][i]:all[0][i+1]])
+			}
+		}
+	}
+
+	a.Outcome = Matched
+	if settings.NoEscape {
+		a.Match = result
+	} else {
+		a.Match = EncodePath(result)
 	}
+	return a
+}
 
-	return ""
+// selected tests whether the rule could be reached by the checks
+// selected by the input filter in the settings. Only literal
+// patterns can be compared to the filter.
+func (r *Rule) selected(settings Settings) bool {
+	return settings.Run != nil && r.Directive == "redirect" &&
+		settings.Run.MatchString(r.Pattern)
 }
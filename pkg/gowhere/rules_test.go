@@ -70,6 +70,31 @@ func TestNewRule(t *testing.T) {
 				re:        true,
 			},
 		},
+
+		{
+			[]string{"redirect", "temp",
+				"/project/def/new_page.html",
+				"/project/def/other_page.html"},
+			Want{
+				directive: "redirect",
+				code:      "302",
+				pattern:   "/project/def/new_page.html",
+				target:    "/project/def/other_page.html",
+				re:        false,
+			},
+		},
+
+		{
+			[]string{"redirect", "Gone",
+				"/project/def/new_page.html"},
+			Want{
+				directive: "redirect",
+				code:      "410",
+				pattern:   "/project/def/new_page.html",
+				target:    "",
+				re:        false,
+			},
+		},
 	}
 
 	for n, test := range tests {
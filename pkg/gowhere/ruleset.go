@@ -1,82 +1,129 @@
 package gowhere
 
-import (
-	"fmt"
-)
-
 // RuleSet holds a group of Rules to be applied together
 type RuleSet struct {
 	rules []Rule
+	// index is used to find the rules that could match a path
+	// without trying all of them
+	index *ruleIndex
+	// dialect, if set, chooses the rule for a path instead of
+	// using the first rule in order to match
+	dialect ruleDialect
+}
+
+// ruleDialect applies rules read from a format where the rule that
+// handles a path is not simply the first one to match it, such as an
+// nginx configuration file.
+type ruleDialect interface {
+	firstMatch(target string, settings Settings) *Match
 }
 
-func (rs *RuleSet) firstMatch(target string, verbose bool) *Match {
-	if verbose {
-		fmt.Printf("\nfirstMatch '%s'\n", target)
+// NewRuleSet creates a RuleSet from the rules, which are applied in
+// the order given.
+func NewRuleSet(rules []Rule) *RuleSet {
+	return &RuleSet{
+		rules: rules,
+		index: newRuleIndex(rules),
 	}
+}
 
-	for _, r := range rs.rules {
-		if verbose {
-			fmt.Printf("checking: '%s' against %s '%s'\n", target,
-				r.Directive, r.Pattern)
-		}
+// candidates returns the positions of the rules that could match the
+// target, in order.
+func (rs *RuleSet) candidates(target string) []int {
+	if rs.index != nil {
+		return rs.index.candidates(target)
+	}
+	all := make([]int, len(rs.rules))
+	for i := range rs.rules {
+		all[i] = i
+	}
+	return all
+}
+
+func (rs *RuleSet) firstMatch(target string, settings Settings) *Match {
+	if rs.dialect != nil {
+		return rs.dialect.firstMatch(target, settings)
+	}
+	settings.trace(Event{Kind: LookupStarted, Path: target})
+
+	for _, i := range rs.candidates(target) {
+		r := &rs.rules[i]
+		settings.trace(Event{Kind: RuleTried, Path: target, Rule: r})
 
-		s := r.Match(target)
-		if s != "" {
-			m := Match{r, s}
+		a := r.Explain(target, settings)
+		if a.Outcome == Matched {
+			settings.trace(Event{Kind: RuleMatched, Path: target,
+				Rule: r, Match: a.Match})
+			m := Match{*r, a.Match}
 			return &m
 		}
 	}
 
+	settings.trace(Event{Kind: NoMatch, Path: target})
 	return nil
 }
 
+// Lookup returns the first Rule to match the path and the destination
+// it redirects to, the way a server would answer a single request, or
+// nil if the path is not redirected.
+func (rs *RuleSet) Lookup(path string, settings Settings) *Match {
+	return rs.firstMatch(path, settings)
+}
+
 // FindMatches locates all of the Rules that match the Check
 func (rs *RuleSet) FindMatches(check *Check, settings Settings) []Match {
+	r, _ := rs.followChain(check, settings)
+	return r
+}
+
+// followChain implements FindMatches, also returning the reason the
+// redirect chain ended (NoMatch, CycleDetected, HopLimitReached,
+// NoTarget, or Rewritten).
+func (rs *RuleSet) followChain(check *Check, settings Settings) ([]Match, EventKind) {
 	var r []Match
 
+	maxHops := check.maxHops(settings)
 	seen := make(map[string]bool)
-	match := rs.firstMatch(check.Input, settings.Verbose)
+	match := rs.firstMatch(check.Input, settings)
 	for {
 		if match == nil {
-			if settings.Verbose {
-				fmt.Printf("no more matches\n")
-			}
-			break
+			return r, NoMatch
 		}
 
-		if settings.Verbose {
-			fmt.Printf("matched: %v\n", *match)
-		}
-
-		if seen[match.Match] {
+		location := NormalizePath(match.Match)
+		if seen[location] {
 			// cycle detected
-			if settings.Verbose {
-				fmt.Printf("cycle\n")
-			}
-			break
+			settings.trace(Event{Kind: CycleDetected, Check: check,
+				Path: match.Match, Rule: &match.Rule, Hops: len(r)})
+			return r, CycleDetected
 		}
 		r = append(r, *match)
-		seen[match.Match] = true
+		seen[location] = true
+
+		if maxHops > 0 && len(r) > maxHops {
+			settings.trace(Event{Kind: HopLimitReached, Check: check,
+				Rule: &match.Rule, Hops: len(r)})
+			return r, HopLimitReached
+		}
 
-		if settings.MaxHops > 0 && len(r) > settings.MaxHops {
-			if settings.Verbose {
-				fmt.Printf("max hops\n")
-			}
-			break
+		if servesTarget(match.Code, match.Match) {
+			// a rewrite or custom error page, which
+			// serves the destination without
+			// redirecting
+			settings.trace(Event{Kind: Rewritten, Check: check,
+				Rule: &match.Rule, Match: match.Match, Hops: len(r)})
+			return r, Rewritten
 		}
 
 		if match.Match == "" {
 			// a redirect that doesn't point to a path,
 			// like code 410
-			if settings.Verbose {
-				fmt.Printf("no-target redirect\n")
-			}
-			break
+			settings.trace(Event{Kind: NoTarget, Check: check,
+				Rule: &match.Rule, Hops: len(r)})
+			return r, NoTarget
 		}
 
 		// look for another item in a redirect chain
-		match = rs.firstMatch(match.Match, settings.Verbose)
+		match = rs.firstMatch(match.Match, settings)
 	}
-
-	return r
 }
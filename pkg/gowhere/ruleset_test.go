@@ -2,6 +2,7 @@ package gowhere
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 )
 
@@ -9,9 +10,9 @@ func TestRuleSetFirstMatchRedirect(t *testing.T) {
 	r, _ := NewRule(1, []string{"redirect", "301",
 		"/project/def/new_page.html",
 		"/project/def/other_page.html"})
-	rs := RuleSet{[]Rule{*r}}
+	rs := RuleSet{rules: []Rule{*r}}
 
-	m := rs.firstMatch("/project/def/new_page.html", true)
+	m := rs.firstMatch("/project/def/new_page.html", Settings{Verbose: true})
 	if m == nil {
 		t.Error("got nil instead of a match")
 	}
@@ -20,7 +21,7 @@ func TestRuleSetFirstMatchRedirect(t *testing.T) {
 			m.Match)
 	}
 
-	m = rs.firstMatch("/project/def/same_page.html", true)
+	m = rs.firstMatch("/project/def/same_page.html", Settings{Verbose: true})
 	if m != nil {
 		t.Errorf("got match for %s instead of nil", m.Match)
 	}
@@ -30,9 +31,9 @@ func TestRuleSetFirstMatchRedirectMatch(t *testing.T) {
 	r, _ := NewRule(1, []string{"redirectmatch", "301",
 		"^/project/([^/]+)/old_page.html$",
 		"/project/$1/new_page.html"})
-	rs := RuleSet{[]Rule{*r}}
+	rs := RuleSet{rules: []Rule{*r}}
 
-	m := rs.firstMatch("/project/def/old_page.html", true)
+	m := rs.firstMatch("/project/def/old_page.html", Settings{Verbose: true})
 	if m == nil {
 		t.Error("got nil instead of a match")
 	}
@@ -41,14 +42,14 @@ func TestRuleSetFirstMatchRedirectMatch(t *testing.T) {
 			m.Match)
 	}
 
-	m = rs.firstMatch("/project/def/same_page.html", true)
+	m = rs.firstMatch("/project/def/same_page.html", Settings{Verbose: true})
 	if m != nil {
 		t.Errorf("got match for %s instead of nil", m.Match)
 	}
 }
 
 func TestRuleSetFindMatchesNone(t *testing.T) {
-	rs := RuleSet{[]Rule{}}
+	rs := RuleSet{rules: []Rule{}}
 	c := Check{
 		LineNum:  1,
 		Input:    "/project/def/old_page.html",
@@ -67,7 +68,7 @@ func TestRuleSetFindMatchesOne(t *testing.T) {
 	r, _ := NewRule(1, []string{"redirectmatch", "301",
 		"^/project/([^/]+)/old_page.html$",
 		"/project/$1/new_page.html"})
-	rs := RuleSet{[]Rule{*r}}
+	rs := RuleSet{rules: []Rule{*r}}
 	c := Check{
 		LineNum:  1,
 		Input:    "/project/def/old_page.html",
@@ -134,3 +135,99 @@ redirect 301 /renamed/new2/ /renamed/old/
 			matches[0].Pattern, matches[2].Match)
 	}
 }
+
+func eventKinds(events eventBuffer) []EventKind {
+	var kinds []EventKind
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+	}
+	return kinds
+}
+
+func TestRuleSetFindMatchesTraceCycle(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /b /a
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	c := Check{LineNum: 1, Input: "/a", Code: "301", Expected: "/b"}
+	var events eventBuffer
+	rs.FindMatches(&c, Settings{Tracer: &events})
+
+	want := []EventKind{
+		LookupStarted, RuleTried, RuleMatched,
+		LookupStarted, RuleTried, RuleMatched,
+		LookupStarted, RuleTried, RuleMatched,
+		CycleDetected,
+	}
+	if !reflect.DeepEqual(eventKinds(events), want) {
+		t.Errorf("got events %v expected %v", eventKinds(events), want)
+	}
+	last := events[len(events)-1]
+	// The cycle is detected when the first redirect is repeated.
+	if last.Rule.LineNum != 1 || last.Path != "/b" {
+		t.Errorf("cycle event has rule %v and path %s", last.Rule, last.Path)
+	}
+}
+
+func TestRuleSetFindMatchesTraceHopLimit(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /b /c
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	c := Check{LineNum: 1, Input: "/a", Code: "301", Expected: "/c"}
+	var events eventBuffer
+	rs.FindMatches(&c, Settings{Tracer: &events, MaxHops: 1})
+
+	last := events[len(events)-1]
+	if last.Kind != HopLimitReached {
+		t.Errorf("got last event %v expected %v", last.Kind, HopLimitReached)
+	}
+	if last.Hops != 2 {
+		t.Errorf("got %d hops expected 2", last.Hops)
+	}
+}
+
+func TestRuleSetLookup(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirect 301 /b /c
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+
+	// Only the first hop is returned.
+	m := rs.Lookup("/a", Settings{})
+	if m == nil || m.Match != "/b" || m.LineNum != 1 {
+		t.Errorf("got %v expected a match to /b from line 1", m)
+	}
+	if m := rs.Lookup("/c", Settings{}); m != nil {
+		t.Errorf("got %v expected nil", m)
+	}
+}
+
+// Rules without a destination, such as a 410, match the path and end
+// the redirect chain. Later rules for the same path are not used.
+func TestRuleSetFindMatchesNoTarget(t *testing.T) {
+	data := []byte(`redirect 410 /gone
+redirect 301 /gone /elsewhere
+redirect 301 /old /gone
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+
+	var tests = []struct {
+		input    string
+		expected []string
+	}{
+		{"/gone", []string{"410 "}},
+		{"/old", []string{"301 /gone", "410 "}},
+	}
+
+	for n, test := range tests {
+		c := Check{LineNum: 1, Input: test.input, Code: "410"}
+		var actual []string
+		for _, m := range rs.FindMatches(&c, Settings{}) {
+			actual = append(actual, m.Code+" "+m.Match)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("test %d: got %q expected %q", n, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package gowhere
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// s3Condition is the Condition of an S3 website routing rule
+type s3Condition struct {
+	KeyPrefixEquals string
+}
+
+// s3Redirect is the Redirect of an S3 website routing rule
+type s3Redirect struct {
+	Protocol             string `json:",omitempty"`
+	HostName             string `json:",omitempty"`
+	ReplaceKeyPrefixWith string `json:",omitempty"`
+	ReplaceKeyWith       string `json:",omitempty"`
+	HttpRedirectCode     string
+}
+
+// s3RoutingRule is one S3 website routing rule
+type s3RoutingRule struct {
+	Condition *s3Condition `json:",omitempty"`
+	Redirect  s3Redirect
+}
+
+// s3PrefixRule describes a regexp rule that S3 can express as a
+// prefix match: the literal prefix of the pattern, and whether the
+// rest of the path is captured as $1.
+func s3PrefixRule(pattern string) (prefix string, captured bool, ok bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false, false
+	}
+	re = re.Simplify()
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+	if len(subs) == 0 || subs[0].Op != syntax.OpBeginText {
+		return "", false, false
+	}
+	subs = subs[1:]
+
+	var literal strings.Builder
+	for len(subs) > 0 && subs[0].Op != syntax.OpCapture &&
+		literalPrefix(subs[0], &literal) {
+		subs = subs[1:]
+	}
+	// An optional rest of the path that starts with a literal, like
+	// the splat of a _redirects rule, is matched with that literal
+	// as part of the prefix.
+	if len(subs) > 0 && subs[0].Op == syntax.OpQuest {
+		inner := subs[0].Sub[0]
+		if inner.Op == syntax.OpConcat && len(inner.Sub) == 2 &&
+			literalPrefix(inner.Sub[0], &literal) {
+			subs = append([]*syntax.Regexp{inner.Sub[1]}, subs[1:]...)
+		}
+	}
+	// The rest of the path, optionally captured as the first group
+	// and followed by the end of the text, may be left over.
+	if len(subs) > 0 && subs[len(subs)-1].Op == syntax.OpEndText {
+		subs = subs[:len(subs)-1]
+	}
+	switch {
+	case len(subs) == 0:
+		return literal.String(), false, true
+	case len(subs) > 1:
+		return "", false, false
+	}
+	rest := subs[0]
+	if rest.Op == syntax.OpCapture && rest.Cap == 1 {
+		captured = true
+		rest = rest.Sub[0]
+	}
+	if rest.Op != syntax.OpStar || !isAnyChar(rest.Sub[0]) {
+		return "", false, false
+	}
+	return literal.String(), captured, true
+}
+
+// S3MaxRoutingRules is the number of routing rules S3 accepts in the
+// website configuration of a bucket
+const S3MaxRoutingRules = 50
+
+// s3Key converts a path to an S3 object key, which has no leading
+// slash
+func s3Key(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
+
+// s3Destination sets the host and key of the redirect from the
+// destination of a rule, which may be a full URL
+func s3Destination(redirect *s3Redirect, target string, prefix bool) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("S3 cannot add a query string or fragment to the destination")
+	}
+	if u.IsAbs() {
+		redirect.Protocol = u.Scheme
+		redirect.HostName = u.Host
+	}
+	key := s3Key(DecodePath(u.EscapedPath()))
+	if prefix {
+		redirect.ReplaceKeyPrefixWith = key
+	} else {
+		redirect.ReplaceKeyWith = key
+	}
+	return nil
+}
+
+// s3Rule converts a Rule to an S3 routing rule, returning the prefix
+// of the paths it matches and whether the rule matches more paths in
+// S3 than it does in the RuleSet.
+func s3Rule(r *Rule) (rule s3RoutingRule, prefix string, broader bool, err error) {
+	code, err := httpStatus(r.Code)
+	if err != nil {
+		return rule, "", false, err
+	}
+	if code < 300 || code > 399 || r.Target == "" {
+		return rule, "", false, fmt.Errorf("S3 only supports redirects, not status %d", code)
+	}
+	rule.Redirect.HttpRedirectCode = strconv.Itoa(code)
+
+	if _, ok := r.literalPath(); !ok && r.Directive == "netlify" {
+		converted := r.redirectMatch()
+		r = &converted
+	}
+	captured := false
+	if path, ok := r.literalPath(); ok {
+		// S3 compares the prefix of the key, so a rule for one
+		// path also applies to the paths beginning with it.
+		prefix, broader = path, true
+		err = s3Destination(&rule.Redirect,
+			r.Explain(path, Settings{NoEscape: true}).Match, false)
+	} else if r.Directive != "redirectmatch" {
+		return rule, "", false, fmt.Errorf("S3 cannot match the pattern")
+	} else if prefix, captured, ok = s3PrefixRule(r.Pattern); !ok {
+		return rule, "", false, fmt.Errorf(
+			"S3 can only match a literal prefix, optionally followed by (.*)")
+	} else if captured && strings.HasSuffix(r.Target, "$1") &&
+		!strings.Contains(strings.TrimSuffix(r.Target, "$1"), "$") {
+		err = s3Destination(&rule.Redirect, strings.TrimSuffix(r.Target, "$1"), true)
+	} else if strings.Contains(r.Target, "$") {
+		return rule, "", false, fmt.Errorf(
+			"S3 can only add the rest of the path ($1) to the end of the destination")
+	} else {
+		err = s3Destination(&rule.Redirect, r.Target, false)
+	}
+	if err != nil {
+		return rule, "", false, err
+	}
+	if key := s3Key(prefix); key != "" {
+		rule.Condition = &s3Condition{KeyPrefixEquals: key}
+	}
+	return rule, prefix, broader, nil
+}
+
+// WriteS3RoutingRules writes the rules as the JSON routing rules of an
+// S3 static website, in the format used by the redirection rules of
+// the bucket's website configuration. S3 applies the first routing
+// rule whose prefix matches the path, so a rule for a literal path
+// becomes a rule for every path beginning with it, and a regular
+// expression rule is only converted if it is a literal prefix that is
+// optionally followed by (.*), with $1 at the end of the destination.
+// The warnings describe the rules that cannot be converted, such as
+// other regular expressions and rules that do not redirect, every rule
+// S3 would apply to more paths (naming a path handled by a later rule
+// when there is one), and the rules beyond the S3MaxRoutingRules S3
+// accepts.
+func WriteS3RoutingRules(out io.Writer, rules *RuleSet) ([]ConversionWarning, error) {
+	var warnings conversionWarnings
+
+	droppedRewrites(rules, &warnings)
+
+	routingRules := []s3RoutingRule{}
+	var broader []int
+	prefixes := make(map[int]string)
+	for i := range rules.rules {
+		r := &rules.rules[i]
+		if err := nginxTargetError(rules, r); err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		}
+		rule, prefix, b, err := s3Rule(r)
+		if err != nil {
+			warnings.add(*r, "cannot convert: %v", err)
+			continue
+		}
+		routingRules = append(routingRules, rule)
+		if p := strings.TrimSuffix(prefix, "/"); p != prefix &&
+			r.Explain(EncodePath(p), Settings{}).Outcome == Matched {
+			warnings.add(*r, "S3 does not apply this rule to %s, without the trailing slash", p)
+		}
+		if len(routingRules) == S3MaxRoutingRules+1 {
+			warnings.add(*r, "S3 accepts at most %d routing rules, so this rule and the ones after it are not used",
+				S3MaxRoutingRules)
+		}
+		if b {
+			broader = append(broader, i)
+			prefixes[i] = prefix
+		}
+	}
+
+	for _, i := range broader {
+		shadowed := false
+	later:
+		for j := i + 1; j < len(rules.rules); j++ {
+			for _, p := range rules.rules[j].probePaths() {
+				p = DecodePath(p)
+				if p != prefixes[i] && strings.HasPrefix(p, prefixes[i]) {
+					warnings.add(rules.rules[i], "S3 also applies this rule to paths such as %s, handled by %s",
+						p, rules.rules[j].String())
+					shadowed = true
+					break later
+				}
+			}
+		}
+		if !shadowed {
+			warnings.add(rules.rules[i], "S3 also applies this rule to every path beginning with %s",
+				prefixes[i])
+		}
+	}
+
+	data, err := json.MarshalIndent(routingRules, "", "  ")
+	if err != nil {
+		return warnings.sorted(), err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return warnings.sorted(), err
+}
@@ -0,0 +1,233 @@
+package gowhere
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestS3PrefixRule(t *testing.T) {
+	var tests = []struct {
+		pattern  string
+		prefix   string
+		captured bool
+		ok       bool
+	}{
+		{"^/docs/(.*)$", "/docs/", true, true},
+		{"^/docs/(.*)", "/docs/", true, true},
+		{"^/docs/", "/docs/", false, true},
+		{"^/docs/.*$", "/docs/", false, true},
+		{"^/docs/index.html$", "", false, false},
+		{"^/(en|fr)/(.*)$", "", false, false},
+		{"^/(docs)/(.*)$", "", false, false},
+		{"/docs/(.*)$", "", false, false},
+		{"^/docs/(.*)\\.html$", "", false, false},
+		{"^/docs(?:/(.*))?$", "/docs/", true, true},
+	}
+
+	for n, test := range tests {
+		prefix, captured, ok := s3PrefixRule(test.pattern)
+		if prefix != test.prefix || captured != test.captured || ok != test.ok {
+			t.Errorf("test %d: got %q %v %v expected %q %v %v",
+				n, prefix, captured, ok, test.prefix, test.captured, test.ok)
+		}
+	}
+}
+
+func TestWriteS3RoutingRules(t *testing.T) {
+	data := []byte(`redirect 301 /a /b
+redirectmatch 302 ^/docs/(.*)$ /new/$1
+redirectmatch 301 ^/old/ https://example.com/archive
+redirect 410 /gone
+redirectmatch 301 ^/(en|fr)/old /$1/new
+redirect 301 /q /r?x=1
+redirect 307 /with%20space /b
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	var out bytes.Buffer
+	warnings, err := WriteS3RoutingRules(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[
+  {
+    "Condition": {
+      "KeyPrefixEquals": "a"
+    },
+    "Redirect": {
+      "ReplaceKeyWith": "b",
+      "HttpRedirectCode": "301"
+    }
+  },
+  {
+    "Condition": {
+      "KeyPrefixEquals": "docs/"
+    },
+    "Redirect": {
+      "ReplaceKeyPrefixWith": "new/",
+      "HttpRedirectCode": "302"
+    }
+  },
+  {
+    "Condition": {
+      "KeyPrefixEquals": "old/"
+    },
+    "Redirect": {
+      "Protocol": "https",
+      "HostName": "example.com",
+      "ReplaceKeyWith": "archive",
+      "HttpRedirectCode": "301"
+    }
+  },
+  {
+    "Condition": {
+      "KeyPrefixEquals": "with space"
+    },
+    "Redirect": {
+      "ReplaceKeyWith": "b",
+      "HttpRedirectCode": "307"
+    }
+  }
+]
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	var lines []int
+	for _, w := range warnings {
+		lines = append(lines, w.Rule.LineNum)
+	}
+	if !reflect.DeepEqual(lines, []int{1, 4, 5, 6, 7}) {
+		t.Errorf("got warnings %v expected lines 1, 4, 5, 6, and 7", warnings)
+	}
+}
+
+func TestWriteS3RoutingRulesBroader(t *testing.T) {
+	data := []byte(`redirect 301 /docs /manual
+redirect 301 /docs/intro /manual/intro
+redirect 301 /about /company
+`)
+	rs, _ := ParseRules(bytes.NewReader(data))
+	var out bytes.Buffer
+	warnings, err := WriteS3RoutingRules(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		line    int
+		message string
+	}{
+		{1, "paths such as /docs/intro"},
+		{2, "every path beginning with /docs/intro"},
+		{3, "every path beginning with /about"},
+	}
+
+	if len(warnings) != len(tests) {
+		t.Fatalf("got warnings %v expected %d", warnings, len(tests))
+	}
+	for n, test := range tests {
+		w := warnings[n]
+		if w.Rule.LineNum != test.line || !strings.Contains(w.Message, test.message) {
+			t.Errorf("test %d: got %v expected line %d with %q", n, w, test.line, test.message)
+		}
+	}
+}
+
+func TestWriteS3RoutingRulesLimit(t *testing.T) {
+	var data bytes.Buffer
+	for i := 1; i <= S3MaxRoutingRules+2; i++ {
+		fmt.Fprintf(&data, "redirectmatch 301 ^/p%d/(.*)$ /new%d/$1\n", i, i)
+	}
+	rs, _ := ParseRules(&data)
+	var out bytes.Buffer
+	warnings, err := WriteS3RoutingRules(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Rule.LineNum != S3MaxRoutingRules+1 {
+		t.Errorf("got warnings %v expected one for line %d", warnings, S3MaxRoutingRules+1)
+	}
+}
+
+func TestWriteS3RoutingRulesNginx(t *testing.T) {
+	data := `rewrite ^/old/(.*)$ /new/$1 last;
+location ^~ /u/ { return 301 /x$uri; }
+location ^~ /docs/ { return 301 /manual; }
+`
+	rs, err := ParseNginx(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	warnings, err := WriteS3RoutingRules(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		line    int
+		message string
+	}{
+		{1, "internal rewrite to /new/$1"},
+		{2, "$uri"},
+	}
+
+	if len(warnings) != len(tests) {
+		t.Fatalf("got warnings %v expected %d", warnings, len(tests))
+	}
+	for n, test := range tests {
+		w := warnings[n]
+		if w.Rule.LineNum != test.line || !strings.Contains(w.Message, test.message) {
+			t.Errorf("test %d: got %v expected line %d with %q", n, w, test.line, test.message)
+		}
+	}
+	if strings.Contains(out.String(), "$uri") {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
+
+func TestWriteS3RoutingRulesNetlify(t *testing.T) {
+	data := "/blog/* /news/:splat\n/posts/:year/:slug /archive/:slug\n"
+	rs, err := ParseNetlify(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	warnings, err := WriteS3RoutingRules(&out, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[
+  {
+    "Condition": {
+      "KeyPrefixEquals": "blog/"
+    },
+    "Redirect": {
+      "ReplaceKeyPrefixWith": "news/",
+      "HttpRedirectCode": "301"
+    }
+  }
+]
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	var tests = []struct {
+		line    int
+		message string
+	}{
+		{1, "to /blog, without the trailing slash"},
+		{2, "cannot convert"},
+	}
+
+	if len(warnings) != len(tests) {
+		t.Fatalf("got warnings %v expected %d", warnings, len(tests))
+	}
+	for n, test := range tests {
+		w := warnings[n]
+		if w.Rule.LineNum != test.line || !strings.Contains(w.Message, test.message) {
+			t.Errorf("test %d: got %v expected line %d with %q", n, w, test.line, test.message)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package gowhere
+
+import (
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// preferredRunes are tried, in order, when choosing a character to
+// represent a character class, so the examples look like real paths.
+const preferredRunes = "abcxyz0123456789-_.ABCXYZ"
+
+// classRune returns a representative rune from a character class
+func classRune(ranges []rune) (rune, bool) {
+	contains := func(c rune) bool {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= c && c <= ranges[i+1] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, c := range preferredRunes {
+		if contains(c) {
+			return c, true
+		}
+	}
+	// Use the first printable rune in the class.
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for c := ranges[i]; c <= ranges[i+1] && c < ranges[i]+256; c++ {
+			if unicode.IsPrint(c) && c != ' ' {
+				return c, true
+			}
+		}
+	}
+	if len(ranges) > 0 {
+		return ranges[0], true
+	}
+	return 0, false
+}
+
+// smallClass is the largest character class for which every
+// character is used as a sample
+const smallClass = 4
+
+// classSize returns the number of characters in a class
+func classSize(ranges []rune) int {
+	n := 0
+	for i := 0; i+1 < len(ranges); i += 2 {
+		n += int(ranges[i+1]-ranges[i]) + 1
+	}
+	return n
+}
+
+// maxSamples limits the number of strings generated for each part of
+// a regexp, and for the whole pattern.
+const maxSamples = 16
+
+// appendSamples adds the values to the samples, without duplicates
+// and up to the limit.
+func appendSamples(samples []string, values ...string) []string {
+	for _, v := range values {
+		if len(samples) >= maxSamples {
+			break
+		}
+		dup := false
+		for _, s := range samples {
+			if s == v {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			samples = append(samples, v)
+		}
+	}
+	return samples
+}
+
+// isAnyChar tests whether the regexp matches any single character
+func isAnyChar(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpAnyChar || re.Op == syntax.OpAnyCharNotNL
+}
+
+// repeatSamples returns the samples of a part repeated n times
+func repeatSamples(sub []string, n int) []string {
+	samples := []string{""}
+	for i := 0; i < n; i++ {
+		samples = concatSamples(samples, sub)
+	}
+	return samples
+}
+
+// concatSamples combines the samples of consecutive parts of a
+// regexp. Rather than every combination, each sample of each part is
+// used at least once, paired with the first sample of the other
+// parts.
+func concatSamples(left, right []string) []string {
+	n := len(left)
+	if len(right) > n {
+		n = len(right)
+	}
+	var samples []string
+	for i := 0; i < n; i++ {
+		l, r := left[0], right[0]
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		samples = appendSamples(samples, l+r)
+	}
+	return samples
+}
+
+// regexpSamples returns strings matching the regexp, covering each
+// branch of the alternations, each character class, and the optional
+// parts, with the simplest string first. Returns nil if no string can
+// match.
+func regexpSamples(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}
+	case syntax.OpCharClass:
+		c, ok := classRune(re.Rune)
+		if !ok {
+			return nil
+		}
+		samples := []string{string(c)}
+		// Alternatives of single characters are parsed as a
+		// class, so cover each character of small classes.
+		if classSize(re.Rune) <= smallClass {
+			for i := 0; i+1 < len(re.Rune); i += 2 {
+				for c := re.Rune[i]; c <= re.Rune[i+1]; c++ {
+					samples = appendSamples(samples, string(c))
+				}
+			}
+		}
+		return samples
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		// A single unescaped dot is usually meant to match a
+		// literal dot, as in a file extension.
+		return []string{"."}
+	case syntax.OpCapture:
+		return regexpSamples(re.Sub[0])
+	case syntax.OpStar, syntax.OpPlus:
+		// Use one repetition first so captured groups are not
+		// empty.
+		sub := []string{"x"}
+		if !isAnyChar(re.Sub[0]) {
+			sub = regexpSamples(re.Sub[0])
+		}
+		if sub == nil {
+			if re.Op == syntax.OpStar {
+				return []string{""}
+			}
+			return nil
+		}
+		samples := appendSamples(nil, sub...)
+		samples = appendSamples(samples, repeatSamples(sub[:1], 2)...)
+		if re.Op == syntax.OpStar {
+			samples = appendSamples(samples, "")
+		}
+		return samples
+	case syntax.OpQuest:
+		// Leave out optional parts first, which also keeps
+		// bounded repetitions to the minimum after simplifying.
+		return appendSamples([]string{""}, regexpSamples(re.Sub[0])...)
+	case syntax.OpRepeat:
+		sub := regexpSamples(re.Sub[0])
+		if sub == nil {
+			if re.Min == 0 {
+				return []string{""}
+			}
+			return nil
+		}
+		n := re.Min
+		if n == 0 && re.Max != 0 {
+			n = 1
+		}
+		return repeatSamples(sub, n)
+	case syntax.OpConcat:
+		samples := []string{""}
+		for _, sub := range re.Sub {
+			subSamples := regexpSamples(sub)
+			if subSamples == nil {
+				return nil
+			}
+			samples = concatSamples(samples, subSamples)
+		}
+		return samples
+	case syntax.OpAlternate:
+		var samples []string
+		for _, sub := range re.Sub {
+			samples = appendSamples(samples, regexpSamples(sub)...)
+		}
+		return samples
+	case syntax.OpNoMatch:
+		return nil
+	}
+	// The empty-width assertions (such as ^ and $) do not add any
+	// text.
+	return []string{""}
+}
+
+// samplePaths returns paths that could match the regexp pattern,
+// simplest first.
+func samplePaths(pattern string) []string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, s := range regexpSamples(re.Simplify()) {
+		// Unanchored patterns may match in the middle of the
+		// path, so try each one as a path of its own as well.
+		paths = append(paths, s, "/"+strings.TrimPrefix(s, "/"))
+	}
+	return paths
+}
+
+// filterPaths returns the unique paths for which the rule either
+// matches or does not, encoded so they can be used in a check file.
+func (r *Rule) filterPaths(paths []string, matching bool) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/") {
+			continue
+		}
+		p = EncodePath(p)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if (r.Explain(p, Settings{}).Outcome == Matched) == matching {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Samples returns input paths that the rule matches: the pattern of a
+// "redirect" rule, or paths synthesized from the regexp of a
+// "redirectmatch" rule to cover each alternative and group. The paths
+// are encoded so they can be used in a check file, and the simplest
+// path is first.
+func (r *Rule) Samples() []string {
+	var candidates []string
+	switch r.Directive {
+	case "redirect":
+		candidates = []string{r.Pattern}
+	case "redirectmatch":
+		candidates = samplePaths(r.Pattern)
+	case "netlify":
+		candidates = samplePaths(r.re.String())
+	}
+	return r.filterPaths(candidates, true)
+}
+
+// NearMisses returns input paths close to the Samples that the rule
+// does not match, such as the samples with a character added or
+// removed at either end.
+func (r *Rule) NearMisses() []string {
+	var candidates []string
+	for _, s := range r.Samples() {
+		decoded := DecodePath(s)
+		candidates = append(candidates,
+			decoded[:len(decoded)-1],
+			decoded+"x",
+			decoded+"/",
+			"/x"+decoded,
+			strings.ToUpper(decoded),
+		)
+	}
+	return r.filterPaths(candidates, false)
+}
+
+// Example returns the simplest input path that the rule matches, or
+// false if no path could be found.
+func (r *Rule) Example() (string, bool) {
+	samples := r.Samples()
+	if len(samples) == 0 {
+		return "", false
+	}
+	return samples[0], true
+}
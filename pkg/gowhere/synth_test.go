@@ -0,0 +1,110 @@
+package gowhere
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExample(t *testing.T) {
+	var tests = []struct {
+		rule     string
+		expected string
+	}{
+		{"redirect 301 /a /b", "/a"},
+		{"redirectmatch 301 ^/a$ /b", "/a"},
+		{"redirectmatch 301 ^/docs/(.*)$ /new/$1", "/docs/x"},
+		{"redirectmatch 301 ^/docs/([^/]+)/index\\.html$ /$1", "/docs/a/index.html"},
+		{"redirectmatch 301 ^/(foo|bar)/\\d{2,4}$ /$1", "/foo/00"},
+		{"redirectmatch 301 ^/a\\x20b$ /b", "/a%20b"},
+		{"redirectmatch 301 legacy /b", "/legacy"},
+		{"redirectmatch 301 ^/old.html$ /new.html", "/old.html"},
+		{"redirectmatch 301 ^/x?$ /b", "/"},
+	}
+
+	for n, test := range tests {
+		rs, err := ParseRules(bytes.NewReader([]byte(test.rule)))
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		r := rs.rules[0]
+		actual, ok := r.Example()
+		if !ok {
+			t.Errorf("test %d: no example found expected %s", n, test.expected)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("test %d: got %s expected %s", n, actual, test.expected)
+		}
+	}
+}
+
+func TestExampleNotFound(t *testing.T) {
+	// The path must start with a slash.
+	rs, err := ParseRules(bytes.NewReader([]byte("redirectmatch 301 ^a /b")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex, ok := rs.rules[0].Example(); ok {
+		t.Errorf("got %s expected no example", ex)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	var tests = []struct {
+		rule     string
+		expected []string
+	}{
+		{"redirect 301 /a /b", []string{"/a"}},
+		{"redirectmatch 301 ^/(en|fr|de)/old$ /$1/new",
+			[]string{"/en/old", "/fr/old", "/de/old"}},
+		{"redirectmatch 301 ^/docs/(.*)$ /new/$1",
+			[]string{"/docs/x", "/docs/xx", "/docs/"}},
+		{"redirectmatch 301 ^/a(/b)?$ /c",
+			[]string{"/a", "/a/b"}},
+		{"redirectmatch 301 ^/(a|b)/(c|d)$ /e",
+			[]string{"/a/c", "/b/d"}},
+		{"redirectmatch 301 ^/(?:x|y)+$ /e",
+			[]string{"/x", "/y", "/xx"}},
+	}
+
+	for n, test := range tests {
+		rs, err := ParseRules(bytes.NewReader([]byte(test.rule)))
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		actual := rs.rules[0].Samples()
+		if strings.Join(actual, " ") != strings.Join(test.expected, " ") {
+			t.Errorf("test %d: got %v expected %v", n, actual, test.expected)
+		}
+	}
+}
+
+func TestNearMisses(t *testing.T) {
+	var tests = []struct {
+		rule     string
+		expected []string
+	}{
+		{"redirect 301 /a /b", []string{"/", "/ax", "/a/", "/x/a", "/A"}},
+		{"redirectmatch 301 ^/(en|fr)/old$ /$1/new",
+			[]string{"/en/ol", "/en/oldx", "/en/old/", "/x/en/old", "/EN/OLD",
+				"/fr/ol", "/fr/oldx", "/fr/old/", "/x/fr/old", "/FR/OLD"}},
+		// Nothing is close enough to miss a pattern matching
+		// anything.
+		{"redirectmatch 301 . /b", nil},
+	}
+
+	for n, test := range tests {
+		rs, err := ParseRules(bytes.NewReader([]byte(test.rule)))
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		actual := rs.rules[0].NearMisses()
+		if strings.Join(actual, " ") != strings.Join(test.expected, " ") {
+			t.Errorf("test %d: got %v expected %v", n, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package gowhere
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// EventKind identifies what happened in an Event
+type EventKind int
+
+const (
+	// CheckStarted is sent before the rules are applied to a Check
+	CheckStarted EventKind = iota
+	// LookupStarted is sent before looking for the first rule
+	// matching a path
+	LookupStarted
+	// RuleTried is sent before a rule is compared to a path
+	RuleTried
+	// RuleMatched is sent when a rule matches a path
+	RuleMatched
+	// NoMatch is sent when no rule matches a path, ending the
+	// redirect chain
+	NoMatch
+	// CycleDetected is sent when a redirect leads back to a path
+	// already in the chain
+	CycleDetected
+	// HopLimitReached is sent when the redirect chain is longer
+	// than the maximum number of hops allowed
+	HopLimitReached
+	// NoTarget is sent when a rule without a destination (such as
+	// a 410) ends the redirect chain
+	NoTarget
+	// CheckFinished is sent after the rules are applied to a Check
+	CheckFinished
+	// Rewritten is sent when a rule serves its destination without
+	// redirecting, such as a rewrite with code 200 or a custom 404
+	// page, ending the redirect chain
+	Rewritten
+)
+
+var eventKindNames = map[EventKind]string{
+	CheckStarted:    "check started",
+	LookupStarted:   "lookup started",
+	RuleTried:       "rule tried",
+	RuleMatched:     "rule matched",
+	NoMatch:         "no match",
+	CycleDetected:   "cycle detected",
+	HopLimitReached: "hop limit reached",
+	NoTarget:        "no target",
+	CheckFinished:   "check finished",
+	Rewritten:       "rewritten",
+}
+
+// Return the name of the EventKind
+func (k EventKind) String() string {
+	if name, ok := eventKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Event describes one step of applying the rules to a Check. Fields
+// that do not apply to the Kind of Event are left empty.
+type Event struct {
+	Kind EventKind
+	// The Check being processed
+	Check *Check
+	// The path being compared to the rules
+	Path string
+	// The rule that was tried or matched
+	Rule *Rule
+	// The destination of the matched rule
+	Match string
+	// The number of hops in the redirect chain so far
+	Hops int
+	// The matches found for the Check, when it is finished
+	Matches []Match
+}
+
+// Tracer receives Events describing the progress of processing the
+// Checks, so they can be logged, displayed, or recorded.
+type Tracer interface {
+	Trace(event Event)
+}
+
+// SlogTracer is a Tracer that sends each Event to a slog.Logger at
+// the debug level.
+type SlogTracer struct {
+	Logger *slog.Logger
+}
+
+// NewSlogTracer creates a SlogTracer using the logger
+func NewSlogTracer(logger *slog.Logger) *SlogTracer {
+	return &SlogTracer{Logger: logger}
+}
+
+// Trace logs the Event
+func (t *SlogTracer) Trace(event Event) {
+	var attrs []slog.Attr
+	if event.Check != nil {
+		attrs = append(attrs,
+			slog.Int("check_line", event.Check.LineNum),
+			slog.String("input", event.Check.Input))
+	}
+	if event.Path != "" {
+		attrs = append(attrs, slog.String("path", event.Path))
+	}
+	if event.Rule != nil {
+		attrs = append(attrs,
+			slog.Int("rule_line", event.Rule.LineNum),
+			slog.String("directive", event.Rule.Directive),
+			slog.String("pattern", event.Rule.Pattern))
+	}
+	if event.Match != "" {
+		attrs = append(attrs, slog.String("match", event.Match))
+	}
+	if event.Hops > 0 {
+		attrs = append(attrs, slog.Int("hops", event.Hops))
+	}
+	t.Logger.LogAttrs(context.Background(), slog.LevelDebug,
+		event.Kind.String(), attrs...)
+}
+
+// defaultTracer is used when Settings.Verbose is set without a
+// Tracer.
+var defaultTracer Tracer = NewSlogTracer(slog.New(
+	slog.NewTextHandler(os.Stderr,
+		&slog.HandlerOptions{Level: slog.LevelDebug})))
+
+// eventBuffer is a Tracer that holds the Events so they can be
+// replayed later.
+type eventBuffer []Event
+
+func (b *eventBuffer) Trace(event Event) {
+	*b = append(*b, event)
+}
+
+// replay sends all of the buffered Events to the tracer
+func (b *eventBuffer) replay(tracer Tracer) {
+	for _, event := range *b {
+		tracer.Trace(event)
+	}
+}
@@ -0,0 +1,117 @@
+package gowhere
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// TrafficPath holds one unique path requested in the traffic and how
+// many times it was requested.
+type TrafficPath struct {
+	Path string
+	Hits int
+}
+
+// accessLogRE matches the start of a line in the Apache common or
+// combined log format, capturing the request target.
+var accessLogRE = regexp.MustCompile(
+	`^\S+ \S+ \S+ \[[^\]]*\] "\S+ (\S+)(?: [^"]*)?" \d{3} `)
+
+// requestPath returns the path of a URL or request target, without
+// the query string or fragment, or "" if the value is not a path.
+func requestPath(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return ""
+		}
+		if u.EscapedPath() == "" {
+			return "/"
+		}
+		return u.EscapedPath()
+	}
+	if !strings.HasPrefix(value, "/") {
+		return ""
+	}
+	if i := strings.IndexAny(value, "?#"); i >= 0 {
+		value = value[:i]
+	}
+	return value
+}
+
+// trafficLinePath finds the requested path on one line of a URL
+// list, access log, or CSV file.
+func trafficLinePath(line string) string {
+	if m := accessLogRE.FindStringSubmatch(line); m != nil {
+		return requestPath(m[1])
+	}
+	if p := requestPath(line); p != "" && !strings.ContainsAny(line, ", \t") {
+		return p
+	}
+
+	// Look for the first field of a CSV or TSV row that is a
+	// path or URL.
+	r := csv.NewReader(strings.NewReader(line))
+	if !strings.Contains(line, ",") && strings.Contains(line, "\t") {
+		r.Comma = '\t'
+	}
+	r.LazyQuotes = true
+	fields, err := r.Read()
+	if err != nil {
+		return ""
+	}
+	for _, f := range fields {
+		if p := requestPath(f); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// ParseTraffic reads the paths requested from a list of URLs or
+// paths, an Apache access log in the common or combined format, or a
+// CSV or TSV file with the path or URL in one of the columns. Blank
+// lines, comments, and CSV header rows are ignored. Returns the unique
+// paths in the order they first appear, with the number of times each
+// was requested, and the number of other lines that were skipped
+// because they do not have a path (such as log entries for requests
+// that timed out before sending one).
+func ParseTraffic(fd io.Reader) ([]TrafficPath, int, error) {
+	var paths []TrafficPath
+	positions := make(map[string]int)
+	skipped := 0
+	input := bufio.NewScanner(fd)
+	for input.Scan() {
+		line := strings.Trim(input.Text(), " \t\r\n")
+
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] == '#' {
+			continue
+		}
+
+		p := trafficLinePath(line)
+		if p == "" {
+			if len(paths) == 0 && strings.ContainsAny(line, ",\t") {
+				// header row
+				continue
+			}
+			skipped++
+			continue
+		}
+
+		if i, ok := positions[p]; ok {
+			paths[i].Hits++
+			continue
+		}
+		positions[p] = len(paths)
+		paths = append(paths, TrafficPath{Path: p, Hits: 1})
+	}
+	return paths, skipped, input.Err()
+}
@@ -0,0 +1,64 @@
+package gowhere
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTraffic(t *testing.T) {
+	data := `# mixed inputs
+/plain/path
+/plain/path?with=query
+https://docs.example.com/from/url.html#section
+127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /common/log.html HTTP/1.0" 200 2326
+127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /combined/log.html?x=1 HTTP/1.1" 301 - "http://example.com/start.html" "Mozilla/4.08"
+"2024-01-01",/from/csv.html,12
+2024-01-01	https://example.com/from/tsv.html
+`
+	paths, _, err := ParseTraffic(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	want := []TrafficPath{
+		{"/plain/path", 2},
+		{"/from/url.html", 1},
+		{"/common/log.html", 1},
+		{"/combined/log.html", 1},
+		{"/from/csv.html", 1},
+		{"/from/tsv.html", 1},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v expected %v", paths, want)
+	}
+}
+
+func TestParseTrafficCSVHeader(t *testing.T) {
+	data := "date,url\n2024-01-01,/a\n"
+	paths, _, err := ParseTraffic(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	if len(paths) != 1 || paths[0].Path != "/a" {
+		t.Errorf("got %v expected /a", paths)
+	}
+}
+
+func TestParseTrafficSkipped(t *testing.T) {
+	data := `/a
+not a path
+127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "-" 408 0
+127.0.0.1 - - [10/Oct/2000:13:55:37 -0700] "GET /b HTTP/1.1" 200 12
+`
+	paths, skipped, err := ParseTraffic(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+	want := []TrafficPath{{"/a", 1}, {"/b", 1}}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v expected %v", paths, want)
+	}
+	if skipped != 2 {
+		t.Errorf("got %d skipped lines expected 2", skipped)
+	}
+}
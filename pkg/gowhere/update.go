@@ -0,0 +1,114 @@
+package gowhere
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CheckUpdate describes a change to the expected result of a Check so
+// that it matches what the rules do.
+type CheckUpdate struct {
+	// The Check as it was
+	Check Check
+	// The new expected HTTP response code and destination
+	Code     string
+	Expected string
+}
+
+// Updates returns the changes that would make the mismatched Checks
+// pass, ordered by line. Checks that fail for other reasons, such as
+// redirect cycles, cannot be fixed by changing the expected result
+// and are not included.
+func (r *Results) Updates() []CheckUpdate {
+	var updates []CheckUpdate
+	for _, m := range r.Mismatched {
+		actual := CheckFromMatches(m.Check.Input, m.Matches)
+		updates = append(updates, CheckUpdate{
+			Check:    m.Check,
+			Code:     actual.Code,
+			Expected: actual.Expected,
+		})
+	}
+	sort.Slice(updates, func(i, j int) bool {
+		return updates[i].Check.LineNum < updates[j].Check.LineNum
+	})
+	return updates
+}
+
+// fieldRE matches the values on a check line
+var fieldRE = regexp.MustCompile(`\S+`)
+
+// updateLine replaces the code and expected destination on a check
+// line, leaving the rest of the line as it was.
+func updateLine(line string, u CheckUpdate) string {
+	fields := fieldRE.FindAllStringIndex(line, -1)
+	positional := len(fields)
+	for positional > 0 {
+		last := fields[positional-1]
+		if !optionRE.MatchString(line[last[0]:last[1]]) {
+			break
+		}
+		positional--
+	}
+	if positional < 2 {
+		return line
+	}
+
+	code := fields[1]
+	var b strings.Builder
+	b.WriteString(line[:code[0]])
+	b.WriteString(u.Code)
+	switch {
+	case positional == 3 && u.Expected != "":
+		expected := fields[2]
+		b.WriteString(line[code[1]:expected[0]])
+		b.WriteString(u.Expected)
+		b.WriteString(line[expected[1]:])
+	case positional == 3:
+		// Remove the destination and the space before it.
+		b.WriteString(line[fields[2][1]:])
+	case u.Expected != "":
+		b.WriteString(" ")
+		b.WriteString(u.Expected)
+		b.WriteString(line[code[1]:])
+	default:
+		b.WriteString(line[code[1]:])
+	}
+	return b.String()
+}
+
+// UpdateChecks copies a text check file from fd to out, applying the
+// updates to the lines of the Checks they describe. Comments, blank
+// lines, options, and spacing are kept as they were.
+func UpdateChecks(fd io.Reader, out io.Writer, updates []CheckUpdate) error {
+	byLine := make(map[int]CheckUpdate)
+	for _, u := range updates {
+		byLine[u.Check.LineNum] = u
+	}
+
+	input := bufio.NewReader(fd)
+	lineNum := 0
+	for {
+		line, err := input.ReadString('\n')
+		if len(line) > 0 {
+			lineNum++
+			if u, ok := byLine[lineNum]; ok {
+				// Keep the line ending as it was.
+				content := strings.TrimRight(line, "\r\n")
+				line = updateLine(content, u) + line[len(content):]
+			}
+			if _, werr := io.WriteString(out, line); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
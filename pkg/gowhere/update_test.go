@@ -0,0 +1,56 @@
+package gowhere
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUpdateLine(t *testing.T) {
+	var tests = []struct {
+		line     string
+		code     string
+		expected string
+		result   string
+	}{
+		{"/a 301 /b", "302", "/c", "/a 302 /c"},
+		{"/a\t301   /b  tags=x  ", "301", "/c", "/a\t301   /c  tags=x  "},
+		{"/a 301 /b exact=true", "410", "", "/a 410 exact=true"},
+		{"/a 410", "301", "/b", "/a 301 /b"},
+		{"/a 410 tags=x", "301", "/b", "/a 301 /b tags=x"},
+		{"  /a 200", "200", "", "  /a 200"},
+	}
+
+	for n, test := range tests {
+		actual := updateLine(test.line, CheckUpdate{Code: test.code, Expected: test.expected})
+		if actual != test.result {
+			t.Errorf("test %d: got %q expected %q", n, actual, test.result)
+		}
+	}
+}
+
+func TestUpdateChecks(t *testing.T) {
+	rules := []byte(`redirect 301 /a /new-b
+redirect 302 /c /d
+redirect 410 /gone
+`)
+	data := "# checks\r\n\r\n/a 301 /b\r\n/c   302   /d   tags=ok\r\n/gone 301 /somewhere\r\n/x 301 /y"
+	rs, _ := ParseRules(bytes.NewReader(rules))
+	checks, err := ParseChecks(bytes.NewReader([]byte(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := ProcessChecks(rs, checks, Settings{})
+	updates := results.Updates()
+	if len(updates) != 3 {
+		t.Fatalf("got %d updates expected 3: %v", len(updates), updates)
+	}
+
+	var out bytes.Buffer
+	if err := UpdateChecks(bytes.NewReader([]byte(data)), &out, updates); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# checks\r\n\r\n/a 301 /new-b\r\n/c   302   /d   tags=ok\r\n/gone 410\r\n/x 200"
+	if out.String() != expected {
+		t.Errorf("got %q expected %q", out.String(), expected)
+	}
+}
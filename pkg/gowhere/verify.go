@@ -0,0 +1,184 @@
+package gowhere
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// liveHopLimit is the most redirects followed for a Check on a live
+// server when there is no limit in the Check or Settings
+const liveHopLimit = 20
+
+// DefaultVerifyTimeout is how long a Verifier waits for each response,
+// unless its Client sets a Timeout.
+const DefaultVerifyTimeout = 30 * time.Second
+
+// Verifier sends the inputs of Checks to a live web server and
+// records the redirects it returns.
+type Verifier struct {
+	// The scheme, host, and optional path prefix of the server,
+	// such as "http://localhost:8080"
+	BaseURL string
+	// The client used to send the requests. Redirects are never
+	// followed automatically. If nil, http.DefaultClient is used.
+	// DefaultVerifyTimeout is used if the client has no Timeout.
+	Client *http.Client
+}
+
+// NewVerifier creates a Verifier for the server at the base URL
+func NewVerifier(baseURL string) (*Verifier, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("base URL %q must start with http:// or https://", baseURL)
+	}
+	return &Verifier{BaseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+// client returns a copy of the HTTP client that does not follow
+// redirects and gives up on a server that does not answer
+func (v *Verifier) client() *http.Client {
+	var c http.Client
+	if v.Client != nil {
+		c = *v.Client
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultVerifyTimeout
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &c
+}
+
+// location converts the Location header of a response to a path on
+// the server, if it points to the server, or returns the absolute URL
+// otherwise.
+func (v *Verifier) location(req *http.Request, header string) string {
+	u, err := req.URL.Parse(header)
+	if err != nil {
+		return header
+	}
+	base, _ := url.Parse(v.BaseURL)
+	if u.Host != base.Host || u.Scheme != base.Scheme {
+		return u.String()
+	}
+	location := strings.TrimPrefix(u.EscapedPath(), base.EscapedPath())
+	if !strings.HasPrefix(location, "/") {
+		location = "/" + location
+	}
+	if u.RawQuery != "" {
+		location += "?" + u.RawQuery
+	}
+	return location
+}
+
+// get sends one request and returns the hop it represents, or nil if
+// the response is successful (2xx) and so is not redirected. Errors
+// such as a 404 or 500 are returned as a hop without a destination,
+// like a 410 rule, so they do not pass for a page that is served.
+func (v *Verifier) get(client *http.Client, path string) (*Match, error) {
+	resp, err := client.Get(v.BaseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	// Read the rest of the body so the connection can be reused.
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	code := strconv.Itoa(resp.StatusCode)
+	header := resp.Header.Get("Location")
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil, nil
+	case resp.StatusCode >= 300 && resp.StatusCode < 400 && header != "":
+		location := v.location(resp.Request, header)
+		return &Match{Rule{Code: code, Pattern: path, Target: location}, location}, nil
+	}
+	return &Match{Rule{Code: code, Pattern: path}, ""}, nil
+}
+
+// FindMatches requests the input of the Check from the server and
+// follows the redirects, returning a Match for each hop in the same
+// form as RuleSet.FindMatches. Each Match holds the HTTP status code,
+// requested path, and Location header in its Rule, which has no line
+// number.
+func (v *Verifier) FindMatches(check *Check, settings Settings) ([]Match, error) {
+	var r []Match
+
+	client := v.client()
+	maxHops := check.maxHops(settings)
+	if maxHops <= 0 {
+		maxHops = liveHopLimit
+	}
+	seen := make(map[string]bool)
+	path := check.Input
+	for {
+		match, err := v.get(client, path)
+		if err != nil {
+			return r, err
+		}
+		if match == nil {
+			return r, nil
+		}
+
+		location := NormalizePath(match.Match)
+		if seen[location] {
+			// cycle detected
+			return r, nil
+		}
+		r = append(r, *match)
+		seen[location] = true
+
+		if len(r) > maxHops || match.Match == "" ||
+			!strings.HasPrefix(match.Match, "/") {
+			// too many hops, a response without a
+			// destination, or a redirect to another
+			// server
+			return r, nil
+		}
+		path = match.Match
+	}
+}
+
+// LiveChain holds the redirect chain a live server returned for a
+// Check
+type LiveChain struct {
+	Check   Check
+	Matches []Match
+}
+
+// VerifyChecks sends the checks to the server and compares the
+// redirects returned to what the checks expect, producing Results in
+// the same form as ProcessChecks. There are no Rules, so Matched and
+// Unmatched are always empty. Also returns the chain for each check
+// that was sent, to pass to CrossCheck. Stops on the first request
+// that fails.
+func (v *Verifier) VerifyChecks(checks []Check, settings Settings) (*Results, []LiveChain, error) {
+	r := Results{}
+	var chains []LiveChain
+	for _, check := range checks {
+		if !check.Selected(settings) {
+			r.Skipped = append(r.Skipped, check)
+			continue
+		}
+		settings.trace(Event{Kind: CheckStarted, Check: &check})
+		matches, err := v.FindMatches(&check, settings)
+		if err != nil {
+			return &r, chains, fmt.Errorf("Could not verify check on line %d: %v",
+				check.LineNum, err)
+		}
+		settings.trace(Event{Kind: CheckFinished, Check: &check,
+			Hops: len(matches), Matches: matches})
+		r.addResult(check, matches, settings)
+		chains = append(chains, LiveChain{check, matches})
+	}
+	return &r, chains, nil
+}
@@ -0,0 +1,170 @@
+package gowhere
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// redirectServer answers with the redirects in the map, which holds
+// the code and Location for each path.
+func redirectServer(redirects map[string][2]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r, ok := redirects[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r[1] != "" {
+			w.Header().Set("Location", r[1])
+		}
+		code, _ := strconv.Atoi(r[0])
+		w.WriteHeader(code)
+	}))
+}
+
+func TestVerifierFindMatches(t *testing.T) {
+	server := redirectServer(map[string][2]string{
+		"/a":       {"301", "/b"},
+		"/b":       {"302", "c"},
+		"/c":       {"301", "http://example.com/d"},
+		"/gone":    {"410", ""},
+		"/missing": {"404", ""},
+		"/broken":  {"503", ""},
+		"/cycle/a": {"301", "/cycle/b"},
+		"/cycle/b": {"301", "/cycle/a"},
+	})
+	defer server.Close()
+
+	v, err := NewVerifier(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		input    string
+		expected []string
+	}{
+		{"/a", []string{"301 /b", "302 /c", "301 http://example.com/d"}},
+		{"/gone", []string{"410 "}},
+		{"/missing", []string{"404 "}},
+		{"/broken", []string{"503 "}},
+		{"/ok", nil},
+		{"/cycle/a", []string{"301 /cycle/b", "301 /cycle/a"}},
+	}
+
+	for n, test := range tests {
+		matches, err := v.FindMatches(&Check{Input: test.input}, Settings{})
+		if err != nil {
+			t.Errorf("test %d: %v", n, err)
+			continue
+		}
+		var actual []string
+		for _, m := range matches {
+			actual = append(actual, m.Code+" "+m.Match)
+		}
+		if len(actual) != len(test.expected) {
+			t.Errorf("test %d: got %v expected %v", n, actual, test.expected)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != test.expected[i] {
+				t.Errorf("test %d: got %v expected %v", n, actual, test.expected)
+			}
+		}
+	}
+}
+
+// Absolute URLs pointing to the server are converted to paths.
+func TestVerifierAbsoluteLocation(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/a" {
+			http.Redirect(w, req, server.URL+"/b?x=1", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	v, _ := NewVerifier(server.URL + "/")
+	matches, err := v.FindMatches(&Check{Input: "/a"}, Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Match != "/b?x=1" {
+		t.Errorf("got %v expected a redirect to /b?x=1", matches)
+	}
+}
+
+func TestVerifyChecks(t *testing.T) {
+	server := redirectServer(map[string][2]string{
+		"/a":       {"301", "/b"},
+		"/b":       {"301", "/c"},
+		"/gone":    {"410", ""},
+		"/broken":  {"500", ""},
+		"/cycle/a": {"301", "/cycle/b"},
+		"/cycle/b": {"301", "/cycle/a"},
+	})
+	defer server.Close()
+
+	data := []byte(`/a 301 /c
+/a 301 /c max-hops=1
+/gone 410
+/cycle/a 301 /cycle/b
+/ok 200
+/wrong 301 /b
+/broken 200
+`)
+	checks, err := ParseChecks(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := NewVerifier(server.URL)
+	results, chains, err := v.VerifyChecks(checks, Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != len(checks) {
+		t.Errorf("got %d chains expected %d", len(chains), len(checks))
+	}
+	if len(results.ExceededHops) != 1 || results.ExceededHops[0].Check.LineNum != 2 {
+		t.Errorf("got exceeded hops %v", results.ExceededHops)
+	}
+	if len(results.Cycles) != 1 || results.Cycles[0].Check.LineNum != 4 {
+		t.Errorf("got cycles %v", results.Cycles)
+	}
+	var mismatched []int
+	for _, m := range results.Mismatched {
+		mismatched = append(mismatched, m.Check.LineNum)
+	}
+	if len(mismatched) != 2 || mismatched[0] != 6 || mismatched[1] != 7 {
+		t.Errorf("got mismatched %v expected lines 6 and 7", results.Mismatched)
+	}
+}
+
+func TestNewVerifierBadURL(t *testing.T) {
+	if _, err := NewVerifier("localhost:8080"); err == nil {
+		t.Errorf("expected an error for a URL without a scheme")
+	}
+}
+
+func TestVerifierTimeout(t *testing.T) {
+	v, _ := NewVerifier("http://localhost:8080")
+	if timeout := v.client().Timeout; timeout != DefaultVerifyTimeout {
+		t.Errorf("got timeout %v expected %v", timeout, DefaultVerifyTimeout)
+	}
+
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-stalled
+	}))
+	defer server.Close()
+	defer close(stalled)
+
+	v, _ = NewVerifier(server.URL)
+	v.Client = &http.Client{Timeout: 50 * time.Millisecond}
+	if _, err := v.FindMatches(&Check{Input: "/a"}, Settings{}); err == nil {
+		t.Errorf("expected an error from a server that does not answer")
+	}
+}
@@ -0,0 +1,109 @@
+// Package gowheretest provides helpers for testing redirect rules in
+// ordinary Go tests.
+package gowheretest
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+// LoadRules reads the rules from an htaccess file, stopping the test if
+// the file cannot be parsed.
+func LoadRules(t testing.TB, filename string) *gowhere.RuleSet {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Could not read htaccess file %s: %v", filename, err)
+	}
+	defer f.Close()
+	rules, err := gowhere.ParseRules(f)
+	if err != nil {
+		t.Fatalf("Could not parse htaccess file %s: %v", filename, err)
+	}
+	return rules
+}
+
+// describeChain shows each hop of a redirect chain on its own line
+func describeChain(input string, matches []gowhere.Match) string {
+	if len(matches) == 0 {
+		return fmt.Sprintf("\n    %s is not redirected", input)
+	}
+	var b strings.Builder
+	from := input
+	for _, m := range matches {
+		fmt.Fprintf(&b, "\n    %s -> %s %s [line %d]", from, m.Code, m.Match, m.LineNum)
+		from = m.Match
+	}
+	return b.String()
+}
+
+// failure describes why the check failed, or returns "" if it passed
+func failure(rules *gowhere.RuleSet, check gowhere.Check) string {
+	results := gowhere.ProcessChecks(rules, []gowhere.Check{check}, gowhere.Settings{})
+	expected := fmt.Sprintf("'%s' should produce %s", check.Input, check.Code)
+	if check.Expected != "" {
+		expected += fmt.Sprintf(" '%s'", check.Expected)
+	}
+
+	switch {
+	case len(results.Mismatched) > 0:
+		return expected + describeChain(check.Input, results.Mismatched[0].Matches)
+	case len(results.Cycles) > 0:
+		return "redirect cycle: " + expected +
+			describeChain(check.Input, results.Cycles[0].Matches)
+	case len(results.ExceededHops) > 0:
+		return "too many hops: " + expected +
+			describeChain(check.Input, results.ExceededHops[0].Matches)
+	case len(results.Diverged) > 0:
+		d := results.Diverged[0]
+		return fmt.Sprintf("redirect chain diverged at hop %d: '%s' should go %s%s",
+			d.Hop+1, check.Input, strings.Join(check.Chain, " -> "),
+			describeChain(check.Input, d.Matches))
+	}
+	return ""
+}
+
+// AssertRedirect reports an error if the redirect chain for the input
+// does not end with the code and expected destination.
+func AssertRedirect(t testing.TB, rules *gowhere.RuleSet, input string, code int, expected string) {
+	t.Helper()
+	check := gowhere.Check{Input: input, Code: strconv.Itoa(code), Expected: expected}
+	if msg := failure(rules, check); msg != "" {
+		t.Error(msg)
+	}
+}
+
+// AssertNoRedirect reports an error if the input is redirected.
+func AssertNoRedirect(t testing.TB, rules *gowhere.RuleSet, input string) {
+	t.Helper()
+	check := gowhere.Check{Input: input, Code: "200"}
+	if msg := failure(rules, check); msg != "" {
+		t.Error(msg)
+	}
+}
+
+// RunCheckFile runs each check in a test file, in any of the formats
+// read by gowhere.ParseChecksFile, as a subtest named after the check.
+func RunCheckFile(t *testing.T, rules *gowhere.RuleSet, filename string) {
+	t.Helper()
+	checks, err := gowhere.ParseChecksFile(filename)
+	if err != nil {
+		t.Fatalf("Could not parse test file %s: %v", filename, err)
+	}
+	for _, check := range checks {
+		name := check.Name
+		if name == "" {
+			name = fmt.Sprintf("line %d %s", check.LineNum, check.Input)
+		}
+		t.Run(name, func(t *testing.T) {
+			if msg := failure(rules, check); msg != "" {
+				t.Errorf("%s:%d: %s", filename, check.LineNum, msg)
+			}
+		})
+	}
+}
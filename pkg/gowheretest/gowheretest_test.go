@@ -0,0 +1,86 @@
+package gowheretest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhellmann/gowhere/pkg/gowhere"
+)
+
+// recorder collects the errors reported by the assertions so tests can
+// check the failures
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func rules(t *testing.T) *gowhere.RuleSet {
+	data := []byte(`redirect 301 /a /b
+redirect 302 /b /c
+redirect 410 /gone
+redirect 301 /cycle/a /cycle/b
+redirect 301 /cycle/b /cycle/a
+`)
+	rs, err := gowhere.ParseRules(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+func TestAssertRedirect(t *testing.T) {
+	rs := rules(t)
+	AssertRedirect(t, rs, "/a", 302, "/c")
+	AssertRedirect(t, rs, "/gone", 410, "")
+	AssertNoRedirect(t, rs, "/c")
+}
+
+func TestAssertRedirectFailures(t *testing.T) {
+	rs := rules(t)
+	r := &recorder{TB: t}
+	AssertRedirect(r, rs, "/a", 301, "/b")
+	AssertRedirect(r, rs, "/cycle/a", 301, "/cycle/b")
+	AssertNoRedirect(r, rs, "/a")
+
+	expected := []string{
+		"'/a' should produce 301 '/b'\n    /a -> 301 /b [line 1]\n    /b -> 302 /c [line 2]",
+		"redirect cycle: '/cycle/a' should produce 301 '/cycle/b'\n" +
+			"    /cycle/a -> 301 /cycle/b [line 4]\n    /cycle/b -> 301 /cycle/a [line 5]",
+		"'/a' should produce 200\n    /a -> 301 /b [line 1]\n    /b -> 302 /c [line 2]",
+	}
+	if len(r.errors) != len(expected) {
+		t.Fatalf("got %d errors expected %d: %v", len(r.errors), len(expected), r.errors)
+	}
+	for i := range expected {
+		if r.errors[i] != expected[i] {
+			t.Errorf("got %q expected %q", r.errors[i], expected[i])
+		}
+	}
+}
+
+func TestRunCheckFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "tests.txt")
+	data := "# checks\n/a 302 /c\n/gone 410\n/c 200\n"
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	RunCheckFile(t, rules(t), filename)
+}
+
+func TestLoadRules(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "htaccess")
+	if err := os.WriteFile(filename, []byte("redirect 301 /a /b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rs := LoadRules(t, filename)
+	AssertRedirect(t, rs, "/a", 301, "/b")
+}